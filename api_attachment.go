@@ -51,7 +51,7 @@ func (cli *Client) UpdateContentAttachments(contentId string, files []string) er
 }
 
 // 在指定页面创建附件
-func (cli *Client) AttachmentCreate(contentId string, fileList []string) ([]Content, error) {
+func (cli *Client) AttachmentCreate(contentId string, fileList []string) ([]Attachment, error) {
 	if len(fileList) <= 0 {
 		return nil, fmt.Errorf("file list is empty")
 	}
@@ -65,8 +65,7 @@ func (cli *Client) AttachmentCreate(contentId string, fileList []string) ([]Cont
 
 	var info struct {
 		ErrorResp
-		Content
-		Results []Content
+		Results []Attachment
 	}
 	err = json.NewDecoder(resp.Body).Decode(&info)
 	if err != nil {
@@ -81,33 +80,36 @@ func (cli *Client) AttachmentCreate(contentId string, fileList []string) ([]Cont
 }
 
 // 更新指定页面的附件
-func (cli *Client) AttachmentUpdate(contentId, attachmentId, file string) ([]Content, error) {
+func (cli *Client) AttachmentUpdate(contentId, attachmentId, file string) (Attachment, error) {
 	resp, err := cli.ApiPOSTFiles("/content/"+contentId+"/child/attachment/"+attachmentId+"/data", []string{file})
 	if err != nil {
-		return nil, fmt.Errorf("执行请求失败: %s", err)
+		return Attachment{}, fmt.Errorf("执行请求失败: %s", err)
 	}
 
 	defer resp.Body.Close()
 
 	var info struct {
 		ErrorResp
-		Content
-		Results []Content
+		Attachment
 	}
 	err = json.NewDecoder(resp.Body).Decode(&info)
 	if err != nil {
-		return nil, fmt.Errorf("解析响应失败: %s", err)
+		return Attachment{}, fmt.Errorf("解析响应失败: %s", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+		return Attachment{}, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
 	}
 
-	return info.Results, nil
+	return info.Attachment, nil
 }
 
 // 获取指定页面的所有附件
-func (cli *Client) AttachmentsByContentId(contentId string) ([]Content, error) {
+func (cli *Client) AttachmentsByContentId(contentId string) ([]Attachment, error) {
+	if cli.useV2() {
+		return cli.v2AttachmentsByContentId(contentId)
+	}
+
 	query := url.Values{}
 	query.Add("limit", "1000")
 	resp, err := cli.ApiGET("/content/"+contentId+"/child/attachment", query)
@@ -119,8 +121,7 @@ func (cli *Client) AttachmentsByContentId(contentId string) ([]Content, error) {
 
 	var info struct {
 		ErrorResp
-		Content
-		Results []Content
+		Results []Attachment
 	}
 	err = json.NewDecoder(resp.Body).Decode(&info)
 	if err != nil {
@@ -133,3 +134,67 @@ func (cli *Client) AttachmentsByContentId(contentId string) ([]Content, error) {
 
 	return info.Results, nil
 }
+
+// 上传单个附件到指定页面，同名附件存在时上传为新版本
+//
+// name为附件名称，为空时使用文件名；comment为附件的备注
+func (cli *Client) AttachmentUpload(contentId, file, name, comment string) (Attachment, error) {
+	if name == "" {
+		name = filepath.Base(file)
+	}
+
+	attachments, err := cli.AttachmentsByContentId(contentId)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("获取页面原有附件清单失败: %s", err)
+	}
+
+	attachmentId := ""
+	for _, att := range attachments {
+		if att.Title == name {
+			attachmentId = att.Id
+			break
+		}
+	}
+
+	return cli.attachmentUpload(contentId, attachmentId, file, name, comment)
+}
+
+// 上传附件，attachmentId为空时新建附件，否则上传为该附件的新版本
+func (cli *Client) attachmentUpload(contentId, attachmentId, file, name, comment string) (Attachment, error) {
+	path := "/content/" + contentId + "/child/attachment"
+	if attachmentId != "" {
+		path += "/" + attachmentId + "/data"
+	}
+
+	var fields url.Values
+	if comment != "" {
+		fields = url.Values{"comment": {comment}}
+	}
+
+	resp, err := cli.ApiPOSTFilesWithFields(path, []string{file}, []string{name}, fields)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	//新建附件返回附件列表，更新附件返回单个附件
+	var info struct {
+		ErrorResp
+		Attachment
+		Results []Attachment
+	}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Attachment{}, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+	}
+
+	if len(info.Results) > 0 {
+		return info.Results[0], nil
+	}
+	return info.Attachment, nil
+}
@@ -0,0 +1,77 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// 获取指定页面的所有页脚评论
+func (cli *Client) ContentFooterComments(contentId string) ([]Content, error) {
+	if cli.useV2() {
+		return cli.v2FooterComments(contentId)
+	}
+
+	query := url.Values{
+		"limit":  {"1000"},
+		"depth":  {"all"},
+		"expand": {expandString(Expand.Body.Storage, Expand.Version)},
+	}
+	resp, err := cli.ApiGET("/content/"+contentId+"/child/comment", query)
+	if err != nil {
+		return nil, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	var info struct {
+		ErrorResp
+		Results []Content
+	}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return nil, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+	}
+
+	return info.Results, nil
+}
+
+// 在指定页面上添加页脚评论
+func (cli *Client) ContentFooterCommentCreate(contentId, data string) (Content, error) {
+	if cli.useV2() {
+		return cli.v2FooterCommentCreate(contentId, data)
+	}
+
+	comment := Content{
+		Type:      "comment",
+		Container: &Content{Id: contentId, Type: ContentTypePage},
+	}
+	comment.SetStorageBody(data)
+
+	resp, err := cli.ApiPOST("/content", comment)
+	if err != nil {
+		return Content{}, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	var info struct {
+		ErrorResp
+		Content
+	}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return Content{}, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Content{}, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+	}
+
+	return info.Content, nil
+}
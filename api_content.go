@@ -1,14 +1,14 @@
 package confluence
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"html/template"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/go-http/confluence/storage"
 )
 
 // 获取指定ID的内容
@@ -24,7 +24,11 @@ func (cli *Client) ContentByIdWithOpt(id string, opt url.Values) (Content, error
 
 	// 缺省展开version便于后期更新时递增版本号
 	if opt.Get("expand") == "" {
-		opt.Set("expand", "version")
+		opt.Set("expand", string(Expand.Version))
+	}
+
+	if cli.useV2() {
+		return cli.v2ContentById(id, opt)
 	}
 
 	resp, err := cli.ApiGET("/content/"+id, opt)
@@ -46,7 +50,7 @@ func (cli *Client) ContentByIdWithOpt(id string, opt url.Values) (Content, error
 	}
 
 	if info.StatusCode != 0 {
-		return Content{}, fmt.Errorf("[%d]%s", info.StatusCode, info.Message)
+		return Content{}, &ResponseError{info.StatusCode, info.Message}
 	}
 
 	return info.Content, nil
@@ -54,12 +58,14 @@ func (cli *Client) ContentByIdWithOpt(id string, opt url.Values) (Content, error
 
 //获取指定空间、标题的内容
 func (cli *Client) ContentBySpaceAndTitle(space, title string) (Content, error) {
-	q := url.Values{
-		"title":    {title},
-		"spaceKey": {space},
-		"expand":   {"version,body.storage,ancestors"},
+	if cli.useV2() {
+		return cli.v2ContentBySpaceAndTitle(space, title)
 	}
 
+	q := ExpandOpt(Expand.Version, Expand.Body.Storage, Expand.Ancestors)
+	q.Set("title", title)
+	q.Set("spaceKey", space)
+
 	resp, err := cli.ApiGET("/content", q)
 	if err != nil {
 		return Content{}, fmt.Errorf("执行请求失败: %s", err)
@@ -118,6 +124,10 @@ func (cli *Client) ContentCreateInSpace(contentType, space, parentId, title, dat
 		}
 	}
 
+	if cli.useV2() {
+		return cli.v2ContentCreate(content, parentId)
+	}
+
 	resp, err := cli.ApiPOST("/content", content)
 	if err != nil {
 		return Content{}, fmt.Errorf("执行请求失败: %s", err)
@@ -143,6 +153,10 @@ func (cli *Client) ContentCreateInSpace(contentType, space, parentId, title, dat
 
 //更新指定的内容
 func (cli *Client) ContentUpdate(content Content) (Content, error) {
+	if cli.useV2() {
+		return cli.v2ContentUpdate(content)
+	}
+
 	resp, err := cli.ApiPUT("/content/"+content.Id, content)
 	if err != nil {
 		return Content{}, fmt.Errorf("执行请求失败: %s", err)
@@ -160,7 +174,7 @@ func (cli *Client) ContentUpdate(content Content) (Content, error) {
 	}
 
 	if info.StatusCode != 0 {
-		return Content{}, fmt.Errorf("[%d]%s", info.StatusCode, info.Message)
+		return Content{}, &ResponseError{info.StatusCode, info.Message}
 	}
 
 	return info.Content, nil
@@ -168,147 +182,36 @@ func (cli *Client) ContentUpdate(content Content) (Content, error) {
 
 //从指定空间查找或创建指定标题的内容
 func (cli *Client) DrawFile(space, parentId, title, wikiDirPrefix, data string) (Content, error) {
-	//内容中的空行会被Confluence保存时自动去掉
-	//因此前先去掉，以避免对比内容变化时受到影响
-	data = strings.TrimSuffix(strings.TrimPrefix(data, "\n"), "\n")
-
-	//获取当前页面的内容
-	content, err := cli.ContentBySpaceAndTitle(space, title)
-	if err != nil {
-		return Content{}, fmt.Errorf("查找%s出错: %s", title, err)
-	}
-
-	// 不存在则创建
-	if content.Id == "" {
-		return cli.PageCreateInSpace(space, parentId, title, data)
-	}
-
-	// 获取文件的路径
-	pagePath := ""
-	lastAncestorId := ""
-	for _, ancestor := range content.Ancestors {
-		pagePath += "/" + ancestor.Title
-		lastAncestorId = ancestor.Id
+	options := &DrawModifyPageOption{
+		Space:               space,
+		Title:               title,
+		ParentId:            parentId,
+		ConfluenceDirPrefix: wikiDirPrefix,
+		Data:                data,
 	}
-	pagePath += "/" + content.Title
-
-	//存在，但不在指定的路径下，报错结束
-	if !strings.HasPrefix(pagePath, wikiDirPrefix) {
-		return Content{}, fmt.Errorf("一个标题为 '%v' 的页面已经存在于该空间中。为您的页面输入一个不同的标题。", title)
-	} else {
-		//存在：对比内容是否有变化
-		newValue, err := cli.ContentBodyConvertTo(data, "storage", "view")
-		if err != nil {
-			return Content{}, fmt.Errorf("转换新内容失败: %s", err)
-		}
-
-		// 去除原文件的备注宏
-		if content.Body.Storage.Value != "" {
-			content.Body.Storage.Value = strings.Split(content.Body.Storage.Value, ConfluenceNoteSplite)[0]
-		}
-		oldValue, err := cli.ContentBodyConvertTo(content.Body.Storage.Value, "storage", "view")
-		if err != nil {
-			return Content{}, fmt.Errorf("转换旧内容失败: %s", err)
-		}
 
-		if newValue == oldValue && lastAncestorId == parentId {
-			return content, nil
-		}
-
-		// 存在则否则更新
-		content.Space.Key = space
-		content.Version.Number += 1
-		content.Version.Message = time.Now().Local().Format("机器人更新于2006-01-02 15:04:05")
-		content.SetStorageBody(data)
-
-		//设置父页面
-		if parentId != "" {
-			content.Ancestors = []Content{
-				{
-					Id: parentId,
-					Space: Space{
-						Key: content.Space.Key,
-					},
-				},
-			}
-		}
-
-		return cli.ContentUpdate(content)
-	}
+	result, err := NewPageSync(cli).Sync(options)
+	return result.Content, err
 }
 
+// 从指定空间查找或创建指定标题的内容，内容有变化时在末尾添加包含extraInfo的备注宏
 func (cli *Client) DrawFileWithNoteMacro(space, parentId, title, wikiDirPrefix, data, extraInfo string) (Content, error) {
-	//内容中的空行会被Confluence保存时自动去掉
-	//因此前先去掉，以避免对比内容变化时受到影响
-	data = strings.TrimSuffix(strings.TrimPrefix(data, "\n"), "\n")
-
-	//获取当前页面的内容
-	content, err := cli.ContentBySpaceAndTitle(space, title)
-	if err != nil {
-		return Content{}, fmt.Errorf("查找%s出错: %s", title, err)
-	}
-
-	// 不存在则创建
-	if content.Id == "" {
-		data += fmt.Sprintf(ConfluenceNoteMacro, extraInfo)
-		return cli.PageCreateInSpace(space, parentId, title, data)
-	}
-
-	// 获取文件的路径
-	pagePath := ""
-	lastAncestorId := ""
-	for _, ancestor := range content.Ancestors {
-		pagePath += "/" + ancestor.Title
-		lastAncestorId = ancestor.Id
-	}
-	pagePath += "/" + content.Title
-
-	//存在，但不在指定的路径下，报错结束
-	if !strings.HasPrefix(pagePath, wikiDirPrefix) {
-		return Content{}, fmt.Errorf("一个标题为 '%v' 的页面已经存在于该空间中。为您的页面输入一个不同的标题。", title)
-	} else {
-		//存在：对比内容是否有变化
-		newValue, err := cli.ContentBodyConvertTo(data, "storage", "view")
-		if err != nil {
-			return Content{}, fmt.Errorf("转换新内容失败: %s", err)
-		}
-
-		// 去除原文件的备注宏
-		if content.Body.Storage.Value != "" {
-			content.Body.Storage.Value = strings.Split(content.Body.Storage.Value, ConfluenceNoteSplite)[0]
-		}
-		oldValue, err := cli.ContentBodyConvertTo(content.Body.Storage.Value, "storage", "view")
-		if err != nil {
-			return Content{}, fmt.Errorf("转换旧内容失败: %s", err)
-		}
-
-		if newValue == oldValue && lastAncestorId == parentId {
-			return content, nil
-		} else {
-			// 如果确定要更新confluence页面，那么这里添加一个备注宏
-			data += fmt.Sprintf(ConfluenceNoteMacro, extraInfo)
-		}
-
-		// 存在则否则更新
-		content.Space.Key = space
-		content.Version.Number += 1
-		content.Version.Message = time.Now().Local().Format("机器人更新于2006-01-02 15:04:05")
-		content.SetStorageBody(data)
-
-		//设置父页面
-		if parentId != "" {
-			content.Ancestors = []Content{
-				{
-					Id: parentId,
-					Space: Space{
-						Key: content.Space.Key,
-					},
-				},
-			}
-		}
-
-		return cli.ContentUpdate(content)
-	}
+	options := &DrawModifyPageOption{
+		Space:               space,
+		Title:               title,
+		ParentId:            parentId,
+		ConfluenceDirPrefix: wikiDirPrefix,
+		Data:                data,
+	}
+
+	pageSync := NewPageSync(cli)
+	pageSync.Footer = FooterFunc(func(*DrawModifyPageOption) (string, error) {
+		note := NoteMacro{Type: PanelNote, Title: "修改历史", Body: "<p>" + extraInfo + "</p>"}
+		return ConfluenceNoteSplite + note.Storage() + "\n", nil
+	})
+
+	result, err := pageSync.Sync(options)
+	return result.Content, err
 }
 
 type Commit struct {
@@ -330,96 +233,107 @@ type DrawModifyPageOption struct {
 	FileName            string   // 文件名称
 }
 
-//从指定空间查找或创建指定标题的内容
+// 从指定空间查找或创建指定标题的内容，内容有变化时在末尾添加包含修改历史的溯源块
 func (cli *Client) DrawFileWithNewNoteMacro(options *DrawModifyPageOption) (Content, error) {
-	//内容中的空行会被Confluence保存时自动去掉
-	//因此前先去掉，以避免对比内容变化时受到影响
-	data := strings.TrimSuffix(strings.TrimPrefix(options.Data, "\n"), "\n")
+	pageSync := NewPageSync(cli)
+	pageSync.Footer = NewProvenanceFooter()
+
+	result, err := pageSync.Sync(options)
+	return result.Content, err
+}
+
+// 渲染包含最近提交和仓库文件信息的修改历史备注宏，以ConfluenceNoteSplite开头
+func GetConfluenceNoteMacro(options *DrawModifyPageOption) (string, error) {
+	var body strings.Builder
+	body.WriteString("<p>")
+	for _, commit := range options.CommitList {
+		body.WriteString(`<a href="` + storage.EscapeAttr(commit.Href) + `">` + storage.EscapeText(commit.CommitId) + ": </a>")
+		body.WriteString(storage.EscapeText(commit.CommitInfo) + "<br/>")
+	}
+	body.WriteString("</p><br/>")
+
+	body.WriteString(`<p>渲染自<a href="` + storage.EscapeAttr(options.GitUrl) + `">` + storage.EscapeText(options.GitName) + "</a>仓库的")
+	body.WriteString(`<a href="` + storage.EscapeAttr(options.FileUrl) + `">` + storage.EscapeText(options.FileName) + "</a>文件</p>")
+
+	note := NoteMacro{Type: PanelNote, Title: "修改历史", Body: body.String()}
+	return ConfluenceNoteSplite + note.Storage() + "\n", nil
+}
+
+// 复制页面树的选项
+type CopyHierarchyOption struct {
+	CopyAttachments    bool                      `json:"copyAttachments"`
+	CopyPermissions    bool                      `json:"copyPermissions"`
+	CopyProperties     bool                      `json:"copyProperties"`
+	CopyLabels         bool                      `json:"copyLabels"`
+	CopyCustomContents bool                      `json:"copyCustomContents"`
+	DestinationPageId  string                    `json:"destinationPageId"`
+	TitleOptions       *CopyHierarchyTitleOption `json:"titleOptions,omitempty"`
+}
+
+// 复制页面树时的标题处理选项
+type CopyHierarchyTitleOption struct {
+	Prefix  string `json:"prefix,omitempty"`
+	Replace string `json:"replace,omitempty"`
+	Search  string `json:"search,omitempty"`
+}
+
+// 复制指定页面及其所有子页面到目标页面下，复制操作以长任务的方式执行
+func (cli *Client) ContentCopyHierarchy(id string, opt CopyHierarchyOption) (LongTaskSubmission, error) {
+	if opt.DestinationPageId == "" {
+		return LongTaskSubmission{}, fmt.Errorf("未指定目标页面")
+	}
 
-	//获取当前页面的内容
-	content, err := cli.ContentBySpaceAndTitle(options.Space, options.Title)
+	resp, err := cli.ApiPOST("/content/"+id+"/pagehierarchy/copy", opt)
 	if err != nil {
-		return Content{}, fmt.Errorf("查找%s出错: %s", options.Title, err)
+		return LongTaskSubmission{}, fmt.Errorf("执行请求失败: %s", err)
 	}
 
-	// 不存在则创建
-	if content.Id == "" {
-		noteMacro, err := GetConfluenceNoteMacro(options)
-		if err != nil {
-			return Content{}, err
-		}
-		data += noteMacro
-		return cli.PageCreateInSpace(options.Space, options.ParentId, options.Title, data)
-	}
-
-	// 获取文件的路径
-	pagePath := ""
-	lastAncestorId := ""
-	for _, ancestor := range content.Ancestors {
-		pagePath += "/" + ancestor.Title
-		lastAncestorId = ancestor.Id
-	}
-	pagePath += "/" + content.Title
-
-	//存在，但不在指定的路径下，报错结束
-	if !strings.HasPrefix(pagePath, options.ConfluenceDirPrefix) {
-		return Content{}, fmt.Errorf("一个标题为 '%v' 的页面已经存在于该空间中。为您的页面输入一个不同的标题。", options.Title)
-	} else {
-		//存在：对比内容是否有变化
-		newValue, err := cli.ContentBodyConvertTo(data, "storage", "view")
-		if err != nil {
-			return Content{}, fmt.Errorf("转换新内容失败: %s", err)
-		}
+	return decodeLongTaskSubmission(resp)
+}
 
-		oldValue, err := cli.ContentBodyConvertTo(content.Body.Storage.Value, "storage", "view")
-		if err != nil {
-			return Content{}, fmt.Errorf("转换旧内容失败: %s", err)
-		}
+const (
+	MovePositionBefore = "before" //移动到目标页面之前（同级）
+	MovePositionAfter  = "after"  //移动到目标页面之后（同级）
+	MovePositionAppend = "append" //移动为目标页面的最后一个子页面
+)
 
-		// 去除原文件的备注宏
-		if len(strings.Split(oldValue, ConfluenceNoteSplite)) > 1 {
-			oldValue = strings.Split(oldValue, ConfluenceNoteSplite)[0]
-		}
+// 移动指定页面到目标页面的相对位置
+func (cli *Client) ContentMove(id, position, targetId string) error {
+	err := cli.requireCapability("移动内容", func(c Capabilities) bool { return c.ContentMove })
+	if err != nil {
+		return err
+	}
 
-		if newValue == oldValue && lastAncestorId == options.ParentId {
-			return content, nil
-		} else {
-			// 如果确定要更新confluence页面，那么这里添加一个备注宏
-			noteMacro, err := GetConfluenceNoteMacro(options)
-			if err != nil {
-				return Content{}, err
-			}
-			data += noteMacro
-		}
+	resp, err := cli.ApiPUT("/content/"+id+"/move/"+position+"/"+targetId, nil)
+	if err != nil {
+		return fmt.Errorf("执行请求失败: %s", err)
+	}
 
-		// 存在则否则更新
-		content.Space.Key = options.Space
-		content.Version.Number += 1
-		content.Version.Message = time.Now().Local().Format("机器人更新于2006-01-02 15:04:05")
-		content.SetStorageBody(data)
-
-		//设置父页面
-		if options.ParentId != "" {
-			content.Ancestors = []Content{
-				{
-					Id: options.ParentId,
-					Space: Space{
-						Key: content.Space.Key,
-					},
-				},
-			}
-		}
+	defer resp.Body.Close()
 
-		return cli.ContentUpdate(content)
+	if resp.StatusCode != http.StatusOK {
+		var info ErrorResp
+		json.NewDecoder(resp.Body).Decode(&info)
+		return fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
 	}
+
+	return nil
 }
 
-func GetConfluenceNoteMacro(options *DrawModifyPageOption) (string, error) {
-	tpl := template.Must(template.New("Note Macro").Parse(NewConfluenceNoteMacro))
-	outPut := bytes.Buffer{}
-	err := tpl.Execute(&outPut, options)
+// 彻底删除回收站中的内容
+//
+// Confluence以长任务的方式执行删除时返回任务引用，直接删除时返回空的任务引用
+func (cli *Client) ContentPurge(id string) (LongTaskSubmission, error) {
+	query := url.Values{"status": {ContentStatusTrashed}}
+	resp, err := cli.ApiRequest("DELETE", "/content/"+id, query, nil, nil)
 	if err != nil {
-		return "", err
+		return LongTaskSubmission{}, fmt.Errorf("执行请求失败: %s", err)
 	}
-	return outPut.String(), nil
+
+	if resp.StatusCode == http.StatusNoContent {
+		resp.Body.Close()
+		return LongTaskSubmission{}, nil
+	}
+
+	return decodeLongTaskSubmission(resp)
 }
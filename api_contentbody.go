@@ -7,6 +7,11 @@ import (
 
 // 获取指定ID的内容
 func (cli *Client) ContentBodyConvertTo(value, from, to string) (string, error) {
+	err := cli.requireCapability("内容格式转换", func(c Capabilities) bool { return c.BodyConvert })
+	if err != nil {
+		return "", err
+	}
+
 	data := ContentBodyStorage{
 		Value:          value,
 		Representation: from,
@@ -0,0 +1,219 @@
+package confluence
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// 空间导出的类型
+type SpaceExportType string
+
+const (
+	SpaceExportXML  SpaceExportType = "TYPE_XML"  //可导入的XML备份
+	SpaceExportHTML SpaceExportType = "TYPE_HTML" //HTML站点
+	SpaceExportPDF  SpaceExportType = "TYPE_PDF"  //PDF文档
+)
+
+var (
+	exportTaskIdRegexp       = regexp.MustCompile(`taskId=([A-Za-z0-9-]+)`)
+	exportDownloadPathRegexp = regexp.MustCompile(`/download/temp/[^"'<>\s?&]+`)
+)
+
+// 提交空间导出任务，导出以长任务的方式执行，结束后通过SpaceExportDownloadPath获取导出文件
+//
+// Confluence没有提供空间导出的REST接口，因此使用页面上的导出操作，并从跳转地址中获取任务ID
+func (cli *Client) SpaceExport(key string, exportType SpaceExportType) (LongTaskSubmission, error) {
+	err := cli.requireCapability("空间导出", func(c Capabilities) bool { return c.SpaceExport })
+	if err != nil {
+		return LongTaskSubmission{}, err
+	}
+
+	header := url.Values{
+		"X-Atlassian-Token": {"no-check"},
+		"Content-Type":      {"application/x-www-form-urlencoded"},
+	}
+
+	var resp *http.Response
+	switch exportType {
+	case SpaceExportXML, SpaceExportHTML:
+		form := url.Values{
+			"key":             {key},
+			"exportType":      {string(exportType)},
+			"contentOption":   {"all"},
+			"includeComments": {"true"},
+			"synchronous":     {"false"},
+		}
+		resp, err = cli.Request("POST", "/spaces/doexportspace.action", nil, header, strings.NewReader(form.Encode()))
+	case SpaceExportPDF:
+		resp, err = cli.Request("POST", "/spaces/flyingpdf/doflyingpdf.action", url.Values{"key": {key}}, header, nil)
+	default:
+		return LongTaskSubmission{}, fmt.Errorf("不支持的导出类型: %s", exportType)
+	}
+	if err != nil {
+		return LongTaskSubmission{}, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return LongTaskSubmission{}, fmt.Errorf("[%d]%s", resp.StatusCode, resp.Status)
+	}
+
+	//任务ID在跳转后的地址中，部分版本在页面内容中
+	m := exportTaskIdRegexp.FindStringSubmatch(resp.Request.URL.RawQuery)
+	if m == nil {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return LongTaskSubmission{}, fmt.Errorf("读取响应失败: %s", err)
+		}
+		m = exportTaskIdRegexp.FindStringSubmatch(string(body))
+	}
+	if m == nil {
+		return LongTaskSubmission{}, fmt.Errorf("响应中没有导出任务ID")
+	}
+
+	return LongTaskSubmission{Id: m[1]}, nil
+}
+
+// 获取已结束的空间导出任务的导出文件地址（相对于Hostname）
+//
+// 导出文件的地址在任务的消息中，长任务接口没有返回时从任务的状态页面获取
+func (cli *Client) SpaceExportDownloadPath(task LongTask) (string, error) {
+	for _, msg := range task.Messages {
+		if path := exportDownloadPathRegexp.FindString(msg.Translation); path != "" {
+			return path, nil
+		}
+		for _, arg := range msg.Args {
+			if path := exportDownloadPathRegexp.FindString(fmt.Sprint(arg)); path != "" {
+				return path, nil
+			}
+		}
+	}
+
+	resp, err := cli.Request("GET", "/pages/longrunningtaskxml.action", url.Values{"taskId": {task.Id}}, nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("读取响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("[%d]%s", resp.StatusCode, resp.Status)
+	}
+
+	path := exportDownloadPathRegexp.FindString(string(body))
+	if path == "" {
+		return "", fmt.Errorf("任务%s的结果中没有导出文件", task.Id)
+	}
+
+	return path, nil
+}
+
+// 导出空间并下载导出文件，返回导出文件的地址
+//
+// opt用于设置等待导出任务的轮询间隔、超时和进度回调
+func (cli *Client) SpaceExportToFile(ctx context.Context, key string, exportType SpaceExportType, file string, opt *WaitTaskOption) (string, error) {
+	submission, err := cli.SpaceExport(key, exportType)
+	if err != nil {
+		return "", err
+	}
+
+	task, err := cli.WaitForTaskWithOpt(ctx, submission.Id, opt)
+	if err != nil {
+		return "", err
+	}
+	if task.Id == "" {
+		task.Id = submission.Id
+	}
+
+	downloadPath, err := cli.SpaceExportDownloadPath(task)
+	if err != nil {
+		return "", err
+	}
+
+	err = cli.DownloadToFile(downloadPath, file)
+	if err != nil {
+		return "", err
+	}
+
+	return downloadPath, nil
+}
+
+// 导出页面为PDF
+func (cli *Client) ContentExportPDF(id string, w io.Writer) (int64, error) {
+	return cli.contentExport("/spaces/flyingpdf/pdfpageexport.action", id, "application/pdf", w)
+}
+
+// 导出页面为Word文档
+func (cli *Client) ContentExportWord(id string, w io.Writer) (int64, error) {
+	return cli.contentExport("/exportword", id, "application/msword", w)
+}
+
+// 通过页面导出操作导出页面，响应不是期望的文件类型时（如跳转到登录页面）返回错误
+func (cli *Client) contentExport(path, id, mediaType string, w io.Writer) (int64, error) {
+	err := cli.requireCapability("页面导出", func(c Capabilities) bool { return c.PageExport })
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := cli.Request("GET", path, url.Values{"pageId": {id}}, nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("[%d]%s", resp.StatusCode, resp.Status)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, mediaType) {
+		return 0, fmt.Errorf("响应类型%s不是%s", contentType, mediaType)
+	}
+
+	return io.Copy(w, resp.Body)
+}
+
+// 下载指定链接的内容到文件，适用于较大的文件
+func (cli *Client) DownloadToFile(downloadUrl, file string) error {
+	u, err := url.Parse(downloadUrl)
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.Request("GET", u.Path, u.Query(), nil, nil)
+	if err != nil {
+		return fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("[%d]%s", resp.StatusCode, resp.Status)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("创建%s失败: %s", file, err)
+	}
+
+	_, err = io.Copy(f, resp.Body)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("写入%s失败: %s", file, err)
+	}
+
+	return f.Close()
+}
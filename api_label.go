@@ -0,0 +1,75 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// 获取指定内容的所有标签
+func (cli *Client) ContentLabels(contentId string) ([]Label, error) {
+	if cli.useV2() {
+		return cli.v2ContentLabels(contentId)
+	}
+
+	query := url.Values{"limit": {"1000"}}
+	resp, err := cli.ApiGET("/content/"+contentId+"/label", query)
+	if err != nil {
+		return nil, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	return decodeLabelsResp(resp)
+}
+
+// 为指定内容添加标签，v2接口不支持写入标签，因此总是使用v1接口
+func (cli *Client) ContentLabelsAdd(contentId string, names ...string) ([]Label, error) {
+	labels := make([]Label, 0, len(names))
+	for _, name := range names {
+		labels = append(labels, Label{Prefix: "global", Name: name})
+	}
+
+	resp, err := cli.ApiPOST("/content/"+contentId+"/label", labels)
+	if err != nil {
+		return nil, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	return decodeLabelsResp(resp)
+}
+
+// 删除指定内容的标签
+func (cli *Client) ContentLabelDelete(contentId, name string) error {
+	query := url.Values{"name": {name}}
+	resp, err := cli.ApiRequest("DELETE", "/content/"+contentId+"/label", query, nil, nil)
+	if err != nil {
+		return fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("[%d]%s", resp.StatusCode, resp.Status)
+	}
+
+	return nil
+}
+
+// 解析标签列表的响应
+func decodeLabelsResp(resp *http.Response) ([]Label, error) {
+	defer resp.Body.Close()
+
+	var info struct {
+		ErrorResp
+		Results []Label
+	}
+	err := json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return nil, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+	}
+
+	return info.Results, nil
+}
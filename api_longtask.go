@@ -0,0 +1,132 @@
+package confluence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// 等待长任务的选项
+type WaitTaskOption struct {
+	Interval   time.Duration  // 轮询间隔，缺省为1秒
+	Timeout    time.Duration  // 等待超时，缺省不超时（由ctx控制）
+	OnProgress func(LongTask) // 每次获取到任务状态后的回调
+	Retries    int            // 获取任务状态连续失败时的重试次数，缺省为3，小于0时不重试
+}
+
+// 获取指定ID的长任务状态
+func (cli *Client) LongTaskById(id string) (LongTask, error) {
+	resp, err := cli.ApiGET("/longtask/"+id, nil)
+	if err != nil {
+		return LongTask{}, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	var info struct {
+		ErrorResp
+		LongTask
+	}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return LongTask{}, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return LongTask{}, fmt.Errorf("[%d]%s", resp.StatusCode, info.ErrorResp.Message)
+	}
+
+	return info.LongTask, nil
+}
+
+// 等待指定的长任务结束
+func (cli *Client) WaitForTask(ctx context.Context, id string) (LongTask, error) {
+	return cli.WaitForTaskWithOpt(ctx, id, nil)
+}
+
+// 等待指定的长任务结束（可以设置轮询间隔、超时和进度回调）
+//
+// 任务结束但未成功时返回任务信息和错误
+func (cli *Client) WaitForTaskWithOpt(ctx context.Context, id string, opt *WaitTaskOption) (LongTask, error) {
+	if opt == nil {
+		opt = &WaitTaskOption{}
+	}
+
+	interval := opt.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	if opt.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opt.Timeout)
+		defer cancel()
+	}
+
+	retries := opt.Retries
+	if retries == 0 {
+		retries = 3
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		task, err := cli.LongTaskById(id)
+		if err != nil {
+			//网络抖动等临时错误在下一次轮询时重试
+			failures++
+			if failures > retries {
+				return LongTask{}, fmt.Errorf("获取任务%s状态失败: %s", id, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return LongTask{}, fmt.Errorf("等待任务%s结束超时: %s", id, ctx.Err())
+			case <-ticker.C:
+			}
+			continue
+		}
+		failures = 0
+
+		if opt.OnProgress != nil {
+			opt.OnProgress(task)
+		}
+
+		if task.IsFinished() {
+			if !task.Successful {
+				return task, fmt.Errorf("任务%s执行失败: %s", id, task.Message())
+			}
+			return task, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return task, fmt.Errorf("等待任务%s结束超时: %s", id, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// 解析长任务的提交响应
+func decodeLongTaskSubmission(resp *http.Response) (LongTaskSubmission, error) {
+	defer resp.Body.Close()
+
+	var info struct {
+		ErrorResp
+		LongTaskSubmission
+	}
+	err := json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return LongTaskSubmission{}, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return LongTaskSubmission{}, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+	}
+
+	return info.LongTaskSubmission, nil
+}
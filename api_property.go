@@ -0,0 +1,127 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// 获取指定内容的属性，属性不存在时返回空属性
+func (cli *Client) ContentPropertyByKey(contentId, key string) (ContentProperty, error) {
+	resp, err := cli.ApiGET("/content/"+contentId+"/property/"+key, nil)
+	if err != nil {
+		return ContentProperty{}, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ContentProperty{}, nil
+	}
+
+	var info struct {
+		ErrorResp
+		ContentProperty
+	}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return ContentProperty{}, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return ContentProperty{}, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+	}
+
+	return info.ContentProperty, nil
+}
+
+// 设置指定内容的属性，属性不存在时创建，存在时递增版本号更新
+func (cli *Client) ContentPropertySet(contentId, key string, value interface{}) (ContentProperty, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ContentProperty{}, fmt.Errorf("编码属性值失败: %s", err)
+	}
+
+	prop, err := cli.ContentPropertyByKey(contentId, key)
+	if err != nil {
+		return ContentProperty{}, fmt.Errorf("获取原有属性失败: %s", err)
+	}
+
+	var resp *http.Response
+	if prop.Key == "" {
+		resp, err = cli.ApiPOST("/content/"+contentId+"/property", ContentProperty{Key: key, Value: data})
+	} else {
+		version := 1
+		if prop.Version != nil {
+			version = prop.Version.Number + 1
+		}
+		update := ContentProperty{Key: key, Value: data, Version: &Version{Number: version}}
+		resp, err = cli.ApiPUT("/content/"+contentId+"/property/"+key, update)
+	}
+	if err != nil {
+		return ContentProperty{}, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	var info struct {
+		ErrorResp
+		ContentProperty
+	}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return ContentProperty{}, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return ContentProperty{}, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+	}
+
+	return info.ContentProperty, nil
+}
+
+// 删除指定内容的属性
+func (cli *Client) ContentPropertyDelete(contentId, key string) error {
+	resp, err := cli.ApiRequest("DELETE", "/content/"+contentId+"/property/"+key, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("[%d]%s", resp.StatusCode, resp.Status)
+	}
+
+	return nil
+}
+
+// 获取指定内容的所有属性
+func (cli *Client) ContentProperties(contentId string) ([]ContentProperty, error) {
+	query := url.Values{
+		"limit":  {"1000"},
+		"expand": {string(Expand.Version)},
+	}
+	resp, err := cli.ApiGET("/content/"+contentId+"/property", query)
+	if err != nil {
+		return nil, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	var info struct {
+		ErrorResp
+		Results []ContentProperty
+	}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return nil, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+	}
+
+	return info.Results, nil
+}
@@ -0,0 +1,75 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// 使用CQL搜索内容，返回本页结果和下一页的起始位置
+func (cli *Client) ContentSearch(cql string, opt url.Values, start int) ([]Content, int, error) {
+	err := cli.requireCapability("CQL搜索", func(c Capabilities) bool { return c.CQLSearch })
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := url.Values{}
+	for k, v := range opt {
+		query[k] = v
+	}
+	query.Set("cql", cql)
+	query.Set("start", fmt.Sprintf("%d", start))
+
+	resp, err := cli.ApiGET("/content/search", query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("执行请求失败: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var info struct {
+		ErrorResp
+		PageResp
+		Results []Content
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return nil, 0, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+	}
+
+	//是否存在Next链接表示是否包含下一页
+	nextStart := 0
+	if info.Links.Next != "" {
+		nextStart = info.Start + info.Size
+	}
+
+	return info.Results, nextStart, nil
+}
+
+// 使用CQL搜索所有符合条件的内容
+func (cli *Client) AllContentSearch(cql string, opt url.Values) ([]Content, error) {
+	var contents []Content
+
+	start := 0
+	for {
+		results, nextStart, err := cli.ContentSearch(cql, opt, start)
+		if err != nil {
+			return nil, err
+		}
+
+		contents = append(contents, results...)
+
+		if nextStart <= 0 {
+			break
+		}
+
+		start = nextStart
+	}
+
+	return contents, nil
+}
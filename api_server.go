@@ -0,0 +1,151 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 获取服务器信息失败后重新获取的间隔
+const serverInfoRetry = time.Minute
+
+// 获取Confluence服务器的版本和能力信息，结果会缓存在客户端中
+//
+// 优先读取Cloud的/rest/api/settings/systemInfo，失败时读取/rest/applinks/1.0/manifest。
+// 获取失败时在serverInfoRetry内直接返回上次的错误，以免每次调用都重复探测，之后重新获取
+func (cli *Client) ServerInfo() (ServerInfo, error) {
+	cli.serverInfoLock.Lock()
+	defer cli.serverInfoLock.Unlock()
+
+	if cli.serverInfo != nil {
+		return *cli.serverInfo, nil
+	}
+
+	if cli.serverInfoErr != nil && time.Since(cli.serverInfoAt) < serverInfoRetry {
+		return ServerInfo{}, cli.serverInfoErr
+	}
+
+	info, err := cli.cloudServerInfo()
+	if err != nil {
+		info, err = cli.manifestServerInfo()
+		if err != nil {
+			cli.serverInfoErr = fmt.Errorf("获取服务器信息失败: %s", err)
+			cli.serverInfoAt = time.Now()
+			return ServerInfo{}, cli.serverInfoErr
+		}
+	}
+
+	info.detectCapabilities()
+	cli.serverInfo = &info
+	cli.serverInfoErr = nil
+
+	return info, nil
+}
+
+// 设置服务器信息，用于跳过自动检测或在测试时指定服务器版本
+func (cli *Client) SetServerInfo(info ServerInfo) {
+	cli.serverInfoLock.Lock()
+	defer cli.serverInfoLock.Unlock()
+
+	info.detectCapabilities()
+	cli.serverInfo = &info
+	cli.serverInfoErr = nil
+}
+
+// 检查服务器是否支持指定的能力，不支持时返回NotSupportedError
+//
+// 无法获取服务器信息时不做限制，交由实际请求返回错误
+func (cli *Client) requireCapability(feature string, supported func(Capabilities) bool) error {
+	info, err := cli.ServerInfo()
+	if err != nil {
+		return nil
+	}
+
+	if !supported(info.Capabilities) {
+		return &NotSupportedError{Feature: feature, Server: info}
+	}
+
+	return nil
+}
+
+// 通过Cloud的systemInfo接口获取服务器信息
+func (cli *Client) cloudServerInfo() (ServerInfo, error) {
+	resp, err := cli.ApiGET("/settings/systemInfo", nil)
+	if err != nil {
+		return ServerInfo{}, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ServerInfo{}, fmt.Errorf("[%d]%s", resp.StatusCode, resp.Status)
+	}
+
+	var info struct {
+		CloudId     string
+		CommitHash  string
+		BaseUrl     string
+		Edition     string
+		SiteTitle   string
+		BuildNumber string
+	}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return ServerInfo{}, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if info.CloudId == "" {
+		return ServerInfo{}, fmt.Errorf("不是Cloud版本")
+	}
+
+	return ServerInfo{
+		Cloud:       true,
+		Version:     "cloud",
+		BuildNumber: info.BuildNumber,
+		Edition:     info.Edition,
+		BaseUrl:     info.BaseUrl,
+	}, nil
+}
+
+// 通过applinks的manifest接口获取服务器信息
+func (cli *Client) manifestServerInfo() (ServerInfo, error) {
+	header := url.Values{"Accept": {"application/json"}}
+	resp, err := cli.Request("GET", "/rest/applinks/1.0/manifest", nil, header, nil)
+	if err != nil {
+		return ServerInfo{}, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ServerInfo{}, fmt.Errorf("[%d]%s", resp.StatusCode, resp.Status)
+	}
+
+	var info struct {
+		TypeId      string
+		Version     string
+		BuildNumber json.Number
+		Url         string
+	}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return ServerInfo{}, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if info.TypeId != "" && info.TypeId != "confluence" {
+		return ServerInfo{}, fmt.Errorf("不是Confluence服务器: %s", info.TypeId)
+	}
+
+	// Cloud版本的manifest中版本号为1000.x的内部版本
+	cloud := strings.HasPrefix(info.Version, "1000.")
+
+	return ServerInfo{
+		Cloud:       cloud,
+		Version:     info.Version,
+		BuildNumber: info.BuildNumber.String(),
+		BaseUrl:     info.Url,
+	}, nil
+}
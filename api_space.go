@@ -1,14 +1,26 @@
 package confluence
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 )
 
+// 获取空间内容时缺省展开的字段
+var defaultSpaceContentExpand = []Expander{Expand.Body.Storage, Expand.Ancestors, Expand.Version, Expand.Metadata.Labels}
+
 //根据SpaceKey获取空间的信息
 func (cli *Client) SpaceByKey(key string) (Space, error) {
+	if cli.useV2() {
+		space, err := cli.v2SpaceByKey(key)
+		if err != nil {
+			return Space{}, err
+		}
+		return space.toSpace(), nil
+	}
+
 	resp, err := cli.ApiGET("/space/"+key, nil)
 	if err != nil {
 		return Space{}, fmt.Errorf("执行请求失败: %s", err)
@@ -28,12 +40,22 @@ func (cli *Client) SpaceByKey(key string) (Space, error) {
 	return info, nil
 }
 
-//获取空间特定类型的内容
+// 获取空间特定类型的内容（仅支持v1接口，v2接口请使用AllSpaceContents）
 func (cli *Client) SpaceContentByType(key, contentType string, start int) ([]Content, int, error) {
-	query := url.Values{
-		"start":  {fmt.Sprintf("%d", start)},
-		"expand": {"body.storage,ancestors"},
+	return cli.SpaceContentByTypeWithOpt(key, contentType, start, nil)
+}
+
+// 获取空间特定类型的内容（可以设置获取选项，缺省展开内容、父页面、版本和标签）
+func (cli *Client) SpaceContentByTypeWithOpt(key, contentType string, start int, opt url.Values) ([]Content, int, error) {
+	query := url.Values{}
+	for k, v := range opt {
+		query[k] = v
+	}
+	if query.Get("expand") == "" {
+		query.Set("expand", expandString(defaultSpaceContentExpand...))
 	}
+	query.Set("start", fmt.Sprintf("%d", start))
+
 	resp, err := cli.ApiGET("/space/"+key+"/content/"+contentType, query)
 	if err != nil {
 		return nil, 0, fmt.Errorf("执行请求失败: %s", err)
@@ -75,11 +97,20 @@ func (cli *Client) AllSpaceBlogs(key string) ([]Content, error) {
 
 //获取空间所有的内容
 func (cli *Client) AllSpaceContents(key, contentType string) ([]Content, error) {
+	return cli.AllSpaceContentsWithOpt(key, contentType, nil)
+}
+
+// 获取空间所有的内容（可以设置获取选项，如只展开版本和父页面以快速获取页面清单）
+func (cli *Client) AllSpaceContentsWithOpt(key, contentType string, opt url.Values) ([]Content, error) {
+	if cli.useV2() {
+		return cli.v2AllSpaceContents(key, contentType, opt)
+	}
+
 	var pages []Content
 
 	start := 0
 	for {
-		contents, nextStart, err := cli.SpaceContentByType(key, contentType, start)
+		contents, nextStart, err := cli.SpaceContentByTypeWithOpt(key, contentType, start, opt)
 		if err != nil {
 			return nil, err
 		}
@@ -95,3 +126,97 @@ func (cli *Client) AllSpaceContents(key, contentType string) ([]Content, error)
 
 	return pages, nil
 }
+
+// 获取空间所有的页面和博客
+func (cli *Client) allSpacePagesAndBlogs(key string, opt url.Values) ([]Content, error) {
+	var contents []Content
+	for _, contentType := range []string{ContentTypePage, "blogpost"} {
+		list, err := cli.AllSpaceContentsWithOpt(key, contentType, opt)
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, list...)
+	}
+	return contents, nil
+}
+
+// 删除指定空间，删除操作以长任务的方式执行
+func (cli *Client) SpaceDelete(key string) (LongTaskSubmission, error) {
+	resp, err := cli.ApiRequest("DELETE", "/space/"+key, nil, nil, nil)
+	if err != nil {
+		return LongTaskSubmission{}, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	return decodeLongTaskSubmission(resp)
+}
+
+// 获取空间回收站中的页面和博客
+func (cli *Client) SpaceTrashContents(key string) ([]Content, error) {
+	var contents []Content
+	for _, contentType := range []string{ContentTypePage, "blogpost"} {
+		start := 0
+		for {
+			query := url.Values{
+				"spaceKey": {key},
+				"type":     {contentType},
+				"status":   {ContentStatusTrashed},
+				"start":    {fmt.Sprintf("%d", start)},
+			}
+
+			resp, err := cli.ApiGET("/content", query)
+			if err != nil {
+				return nil, fmt.Errorf("执行请求失败: %s", err)
+			}
+
+			var info struct {
+				ErrorResp
+				PageResp
+				Results []Content
+			}
+			err = json.NewDecoder(resp.Body).Decode(&info)
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("解析响应失败: %s", err)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+			}
+
+			contents = append(contents, info.Results...)
+
+			if info.Links.Next == "" {
+				break
+			}
+			start = info.Start + info.Size
+		}
+	}
+
+	return contents, nil
+}
+
+// 清空空间的回收站，逐个彻底删除回收站中的内容，删除以长任务执行时等待任务结束
+//
+// 返回已删除的内容数量，出错时返回出错前已删除的数量
+func (cli *Client) SpaceTrashPurge(ctx context.Context, key string, opt *WaitTaskOption) (int, error) {
+	contents, err := cli.SpaceTrashContents(key)
+	if err != nil {
+		return 0, fmt.Errorf("获取回收站内容失败: %s", err)
+	}
+
+	for i, content := range contents {
+		task, err := cli.ContentPurge(content.Id)
+		if err != nil {
+			return i, fmt.Errorf("删除%s失败: %s", content.Title, err)
+		}
+
+		if task.Id != "" {
+			_, err = cli.WaitForTaskWithOpt(ctx, task.Id, opt)
+			if err != nil {
+				return i, fmt.Errorf("删除%s失败: %s", content.Title, err)
+			}
+		}
+	}
+
+	return len(contents), nil
+}
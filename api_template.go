@@ -0,0 +1,194 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// 获取空间的内容模板，space为空时获取全局模板
+func (cli *Client) PageTemplatesBySpace(space string, start int) ([]ContentTemplate, int, error) {
+	return cli.templatesByType("page", space, start)
+}
+
+// 获取空间的蓝图模板，space为空时获取全局蓝图模板
+func (cli *Client) BlueprintTemplatesBySpace(space string, start int) ([]ContentTemplate, int, error) {
+	return cli.templatesByType("blueprint", space, start)
+}
+
+// 获取空间所有的内容模板
+func (cli *Client) AllPageTemplates(space string) ([]ContentTemplate, error) {
+	return cli.allTemplates(cli.PageTemplatesBySpace, space)
+}
+
+// 获取空间所有的蓝图模板
+func (cli *Client) AllBlueprintTemplates(space string) ([]ContentTemplate, error) {
+	return cli.allTemplates(cli.BlueprintTemplatesBySpace, space)
+}
+
+// 获取指定ID的模板
+func (cli *Client) TemplateById(id string) (ContentTemplate, error) {
+	query := url.Values{"expand": {"body"}}
+	resp, err := cli.ApiGET("/template/"+id, query)
+	if err != nil {
+		return ContentTemplate{}, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	var info struct {
+		ErrorResp
+		ContentTemplate
+	}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return ContentTemplate{}, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return ContentTemplate{}, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+	}
+
+	return info.ContentTemplate, nil
+}
+
+// 创建内容模板，模板的Space为空时创建全局模板
+func (cli *Client) TemplateCreate(tpl ContentTemplate) (ContentTemplate, error) {
+	if tpl.TemplateType == "" {
+		tpl.TemplateType = TemplateTypePage
+	}
+
+	resp, err := cli.ApiPOST("/template", tpl)
+	if err != nil {
+		return ContentTemplate{}, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	return decodeTemplateResp(resp)
+}
+
+// 更新指定的内容模板
+func (cli *Client) TemplateUpdate(tpl ContentTemplate) (ContentTemplate, error) {
+	if tpl.TemplateId == "" {
+		return ContentTemplate{}, fmt.Errorf("未指定模板ID")
+	}
+
+	if tpl.TemplateType == "" {
+		tpl.TemplateType = TemplateTypePage
+	}
+
+	resp, err := cli.ApiPUT("/template", tpl)
+	if err != nil {
+		return ContentTemplate{}, fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	return decodeTemplateResp(resp)
+}
+
+// 删除指定的内容模板
+func (cli *Client) TemplateDelete(id string) error {
+	resp, err := cli.ApiRequest("DELETE", "/template/"+id, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("[%d]%s", resp.StatusCode, resp.Status)
+	}
+
+	return nil
+}
+
+// 使用指定模板在空间中创建页面，vars为模板变量的取值
+func (cli *Client) PageCreateFromTemplate(templateId, space, parentId, title string, vars map[string]string) (Content, error) {
+	tpl, err := cli.TemplateById(templateId)
+	if err != nil {
+		return Content{}, fmt.Errorf("获取模板%s失败: %s", templateId, err)
+	}
+
+	return cli.ContentCreateInSpace(ContentTypePage, space, parentId, title, tpl.Render(vars))
+}
+
+// 获取指定类型的模板
+func (cli *Client) templatesByType(templateType, space string, start int) ([]ContentTemplate, int, error) {
+	query := url.Values{
+		"start":  {fmt.Sprintf("%d", start)},
+		"expand": {"body"},
+	}
+	if space != "" {
+		query.Set("spaceKey", space)
+	}
+
+	resp, err := cli.ApiGET("/template/"+templateType, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("执行请求失败: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("[%d]%s", resp.StatusCode, resp.Status)
+	}
+
+	var info struct {
+		PageResp
+		Results []ContentTemplate
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return nil, 0, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	//是否存在Next链接表示是否包含下一页
+	nextStart := 0
+	if info.Links.Next != "" {
+		nextStart = info.Start + info.Size
+	}
+
+	return info.Results, nextStart, nil
+}
+
+// 分页获取全部模板
+func (cli *Client) allTemplates(fetch func(string, int) ([]ContentTemplate, int, error), space string) ([]ContentTemplate, error) {
+	var templates []ContentTemplate
+
+	start := 0
+	for {
+		results, nextStart, err := fetch(space, start)
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, results...)
+
+		if nextStart <= 0 {
+			break
+		}
+
+		start = nextStart
+	}
+
+	return templates, nil
+}
+
+// 解析模板的创建、更新响应
+func decodeTemplateResp(resp *http.Response) (ContentTemplate, error) {
+	defer resp.Body.Close()
+
+	var info struct {
+		ErrorResp
+		ContentTemplate
+	}
+	err := json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return ContentTemplate{}, fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return ContentTemplate{}, fmt.Errorf("[%d]%s", resp.StatusCode, info.Message)
+	}
+
+	return info.ContentTemplate, nil
+}
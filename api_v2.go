@@ -0,0 +1,165 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	ApiVersionAuto = 0 //根据服务器信息自动选择，Cloud使用v2，其他使用v1
+	ApiVersionV1   = 1 //使用/rest/api接口
+	ApiVersionV2   = 2 //使用Cloud的/api/v2接口
+)
+
+// v2接口的错误响应
+type v2ErrorResp struct {
+	Errors []struct {
+		Status int
+		Code   string
+		Title  string
+		Detail string
+	}
+}
+
+// v2接口的分页响应
+type v2PageResp struct {
+	Results []json.RawMessage
+	Links   struct {
+		Next string
+		Base string
+	} `json:"_links"`
+}
+
+// 错误响应的描述信息
+func (e *v2ErrorResp) message() string {
+	var msgs []string
+	for _, err := range e.Errors {
+		msg := err.Title
+		if err.Detail != "" {
+			msg += ": " + err.Detail
+		}
+		msgs = append(msgs, msg)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// 是否使用v2接口
+func (cli *Client) useV2() bool {
+	switch cli.ApiVersion {
+	case ApiVersionV1:
+		return false
+	case ApiVersionV2:
+		return true
+	}
+
+	info, err := cli.ServerInfo()
+	return err == nil && info.Capabilities.RestV2
+}
+
+// 发起GET类型的v2接口请求
+func (cli *Client) ApiV2GET(path string, query url.Values) (*http.Response, error) {
+	return cli.ApiV2Request("GET", path, query, nil, nil)
+}
+
+// 发起POST类型的v2接口请求
+func (cli *Client) ApiV2POST(path string, data interface{}) (*http.Response, error) {
+	r, err := dataToJsonReader(data)
+	if err != nil {
+		return nil, fmt.Errorf("编码请求数据失败: %s", err)
+	}
+	return cli.ApiV2Request("POST", path, nil, nil, r)
+}
+
+// 发起PUT类型的v2接口请求
+func (cli *Client) ApiV2PUT(path string, data interface{}) (*http.Response, error) {
+	r, err := dataToJsonReader(data)
+	if err != nil {
+		return nil, fmt.Errorf("编码请求数据失败: %s", err)
+	}
+	return cli.ApiV2Request("PUT", path, nil, nil, r)
+}
+
+// 发起指定方法的v2接口请求
+func (cli *Client) ApiV2Request(method, path string, query, header url.Values, body io.Reader) (*http.Response, error) {
+	return cli.Request(method, "/api/v2"+path, query, header, body)
+}
+
+// 执行v2接口请求并解析响应，期望的状态码之外的响应都作为错误返回
+func (cli *Client) v2Do(method, path string, query url.Values, data, out interface{}) error {
+	var resp *http.Response
+	var err error
+
+	switch method {
+	case "POST":
+		resp, err = cli.ApiV2POST(path, data)
+	case "PUT":
+		resp, err = cli.ApiV2PUT(path, data)
+	default:
+		resp, err = cli.ApiV2Request(method, path, query, nil, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("执行请求失败: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var info v2ErrorResp
+		json.NewDecoder(resp.Body).Decode(&info)
+		return &ResponseError{resp.StatusCode, info.message()}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("解析响应失败: %s", err)
+	}
+
+	return nil
+}
+
+// 按游标分页获取v2接口的所有结果，每条结果交由each处理
+func (cli *Client) v2GetAll(path string, query url.Values, each func(json.RawMessage) error) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	if query.Get("limit") == "" {
+		query.Set("limit", "250")
+	}
+
+	for {
+		var info v2PageResp
+		err := cli.v2Do("GET", path, query, nil, &info)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range info.Results {
+			err = each(result)
+			if err != nil {
+				return err
+			}
+		}
+
+		if info.Links.Next == "" {
+			return nil
+		}
+
+		//Next链接形如/wiki/api/v2/pages?cursor=xxx，仅保留游标参数继续请求
+		next, err := url.Parse(info.Links.Next)
+		if err != nil {
+			return fmt.Errorf("解析分页链接失败: %s", err)
+		}
+		query = next.Query()
+		if i := strings.Index(next.Path, "/api/v2"); i >= 0 {
+			path = next.Path[i+len("/api/v2"):]
+		}
+	}
+}
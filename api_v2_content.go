@@ -0,0 +1,310 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// 通过v2接口获取指定ID的内容，依次尝试页面和博客
+func (cli *Client) v2ContentById(id string, opt url.Values) (Content, error) {
+	expand := opt.Get("expand")
+
+	query := url.Values{}
+	if format := v2BodyFormat(expand); format != "" {
+		query.Set("body-format", format)
+	}
+
+	contentType := ContentTypePage
+
+	var page v2Page
+	err := cli.v2Do("GET", "/pages/"+id, query, nil, &page)
+	if err != nil {
+		contentType = "blogpost"
+		if cli.v2Do("GET", "/blogposts/"+id, query, nil, &page) != nil {
+			return Content{}, err
+		}
+	}
+
+	content := page.toContent(contentType)
+
+	if contentType == ContentTypePage && expandHas(expand, "ancestors") {
+		content.Ancestors, err = cli.v2PageAncestors(id)
+		if err != nil {
+			return Content{}, fmt.Errorf("获取父页面失败: %s", err)
+		}
+	}
+
+	return content, nil
+}
+
+// 通过v2接口获取指定空间、标题的页面，未找到时返回空内容
+func (cli *Client) v2ContentBySpaceAndTitle(space, title string) (Content, error) {
+	spaceId, err := cli.v2SpaceId(space)
+	if err != nil {
+		return Content{}, err
+	}
+
+	query := url.Values{
+		"space-id":    {spaceId},
+		"title":       {title},
+		"body-format": {"storage"},
+	}
+
+	var pages []v2Page
+	err = cli.v2GetAll("/pages", query, func(raw json.RawMessage) error {
+		var page v2Page
+		err := json.Unmarshal(raw, &page)
+		pages = append(pages, page)
+		return err
+	})
+	if err != nil {
+		return Content{}, err
+	}
+
+	switch len(pages) {
+	case 0:
+		return Content{}, nil
+	case 1:
+	default:
+		return Content{}, fmt.Errorf("找到%d条记录", len(pages))
+	}
+
+	content := pages[0].toContent(ContentTypePage)
+	content.Space.Key = space
+
+	content.Ancestors, err = cli.v2PageAncestors(content.Id)
+	if err != nil {
+		return Content{}, fmt.Errorf("获取父页面失败: %s", err)
+	}
+
+	return content, nil
+}
+
+// 通过v2接口获取页面的所有父页面，顺序为从顶层到直接父页面
+func (cli *Client) v2PageAncestors(id string) ([]Content, error) {
+	var ids []string
+	err := cli.v2GetAll("/pages/"+id+"/ancestors", nil, func(raw json.RawMessage) error {
+		var ancestor struct{ Id string }
+		err := json.Unmarshal(raw, &ancestor)
+		ids = append(ids, ancestor.Id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	//v2的父页面列表不包含标题，需要逐个获取
+	var ancestors []Content
+	for _, ancestorId := range ids {
+		var page v2Page
+		err = cli.v2Do("GET", "/pages/"+ancestorId, nil, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, page.toContent(ContentTypePage))
+	}
+
+	return ancestors, nil
+}
+
+// 通过v2接口在指定空间创建内容
+func (cli *Client) v2ContentCreate(content Content, parentId string) (Content, error) {
+	spaceId, err := cli.v2SpaceId(content.Space.Key)
+	if err != nil {
+		return Content{}, err
+	}
+
+	data := v2PageWrite{
+		Status:   "current",
+		Title:    content.Title,
+		SpaceId:  spaceId,
+		ParentId: parentId,
+		Body: v2BodyWrite{
+			Representation: "storage",
+			Value:          content.Body.Storage.Value,
+		},
+	}
+
+	var page v2Page
+	err = cli.v2Do("POST", v2ContentPath(content.Type), nil, data, &page)
+	if err != nil {
+		return Content{}, err
+	}
+
+	created := page.toContent(content.Type)
+	created.Space.Key = content.Space.Key
+
+	return created, nil
+}
+
+// 通过v2接口更新指定的内容，父页面取Ancestors的最后一项
+func (cli *Client) v2ContentUpdate(content Content) (Content, error) {
+	data := v2PageWrite{
+		Id:     content.Id,
+		Status: "current",
+		Title:  content.Title,
+		Body: v2BodyWrite{
+			Representation: "storage",
+			Value:          content.Body.Storage.Value,
+		},
+		Version: &v2Version{
+			Number:  content.Version.Number,
+			Message: content.Version.Message,
+		},
+	}
+
+	if n := len(content.Ancestors); n > 0 {
+		data.ParentId = content.Ancestors[n-1].Id
+	}
+
+	var page v2Page
+	err := cli.v2Do("PUT", v2ContentPath(content.Type)+"/"+content.Id, nil, data, &page)
+	if err != nil {
+		return Content{}, err
+	}
+
+	updated := page.toContent(content.Type)
+	updated.Space.Key = content.Space.Key
+
+	return updated, nil
+}
+
+// 通过v2接口获取空间所有指定类型的内容，父页面信息根据parentId在本地计算
+func (cli *Client) v2AllSpaceContents(key, contentType string, opt url.Values) ([]Content, error) {
+	spaceId, err := cli.v2SpaceId(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType == ContentTypeBlog {
+		contentType = "blogpost"
+	}
+
+	expand := expandString(defaultSpaceContentExpand...)
+	if opt.Get("expand") != "" {
+		expand = opt.Get("expand")
+	}
+
+	query := url.Values{}
+	if format := v2BodyFormat(expand); format != "" {
+		query.Set("body-format", format)
+	}
+
+	var pages []v2Page
+	err = cli.v2GetAll("/spaces/"+spaceId+v2ContentPath(contentType), query, func(raw json.RawMessage) error {
+		var page v2Page
+		err := json.Unmarshal(raw, &page)
+		pages = append(pages, page)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pageById := make(map[string]v2Page, len(pages))
+	for _, page := range pages {
+		pageById[page.Id] = page
+	}
+
+	contents := make([]Content, 0, len(pages))
+	for _, page := range pages {
+		content := page.toContent(contentType)
+		content.Space.Key = key
+
+		//沿parentId向上查找，得到从顶层开始的父页面列表
+		for parentId := page.ParentId; parentId != ""; {
+			parent, found := pageById[parentId]
+			if !found {
+				break
+			}
+			content.Ancestors = append([]Content{parent.toContent(ContentTypePage)}, content.Ancestors...)
+			parentId = parent.ParentId
+		}
+
+		contents = append(contents, content)
+	}
+
+	return contents, nil
+}
+
+// 通过v2接口获取内容的所有附件
+func (cli *Client) v2AttachmentsByContentId(contentId string) ([]Attachment, error) {
+	var attachments []Attachment
+	err := cli.v2GetAllOfContent(contentId, "/attachments", func(raw json.RawMessage) error {
+		var att v2Attachment
+		err := json.Unmarshal(raw, &att)
+		attachments = append(attachments, att.toAttachment())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// 通过v2接口获取内容的所有标签
+func (cli *Client) v2ContentLabels(contentId string) ([]Label, error) {
+	var labels []Label
+	err := cli.v2GetAllOfContent(contentId, "/labels", func(raw json.RawMessage) error {
+		var label Label
+		err := json.Unmarshal(raw, &label)
+		labels = append(labels, label)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// 通过v2接口获取页面的所有页脚评论
+func (cli *Client) v2FooterComments(contentId string) ([]Content, error) {
+	query := url.Values{"body-format": {"storage"}}
+
+	var comments []Content
+	err := cli.v2GetAll("/pages/"+contentId+"/footer-comments", query, func(raw json.RawMessage) error {
+		var comment v2Comment
+		err := json.Unmarshal(raw, &comment)
+		comments = append(comments, comment.toContent())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// 通过v2接口在页面上添加页脚评论
+func (cli *Client) v2FooterCommentCreate(contentId, data string) (Content, error) {
+	req := struct {
+		PageId string      `json:"pageId"`
+		Body   v2BodyWrite `json:"body"`
+	}{
+		PageId: contentId,
+		Body:   v2BodyWrite{Representation: "storage", Value: data},
+	}
+
+	var comment v2Comment
+	err := cli.v2Do("POST", "/footer-comments", nil, req, &comment)
+	if err != nil {
+		return Content{}, err
+	}
+
+	return comment.toContent(), nil
+}
+
+// 通过v2接口获取页面或博客的子资源（附件、标签等）
+//
+// 只有内容ID时无法确定内容类型，先按页面获取，页面不存在时按博客获取
+func (cli *Client) v2GetAllOfContent(contentId, child string, fn func(json.RawMessage) error) error {
+	err := cli.v2GetAll("/pages/"+contentId+child, nil, fn)
+	if e, ok := err.(*ResponseError); ok && e.StatusCode == http.StatusNotFound {
+		return cli.v2GetAll("/blogposts/"+contentId+child, nil, fn)
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package confluence
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// 通过v2接口获取指定Key的空间
+func (cli *Client) v2SpaceByKey(key string) (v2Space, error) {
+	var info struct {
+		Results []v2Space
+	}
+
+	err := cli.v2Do("GET", "/spaces", url.Values{"keys": {key}}, nil, &info)
+	if err != nil {
+		return v2Space{}, err
+	}
+
+	if len(info.Results) == 0 {
+		return v2Space{}, fmt.Errorf("空间%s不存在", key)
+	}
+
+	return info.Results[0], nil
+}
+
+// 通过v2接口获取指定Key的空间ID，v2接口中空间均以ID引用
+func (cli *Client) v2SpaceId(key string) (string, error) {
+	space, err := cli.v2SpaceByKey(key)
+	if err != nil {
+		return "", fmt.Errorf("获取空间%s失败: %s", key, err)
+	}
+
+	return space.Id, nil
+}
@@ -0,0 +1,56 @@
+package confluence
+
+// Confluence中的附件
+type Attachment struct {
+	Id         string               `json:"id,omitempty"`
+	Type       string               `json:"type,omitempty"`
+	Status     string               `json:"status,omitempty"`
+	Title      string               `json:"title,omitempty"`
+	Version    Version              `json:"version,omitempty"`
+	Container  *Content             `json:"container,omitempty"`
+	Metadata   *AttachmentMetadata  `json:"metadata,omitempty"`
+	Extensions AttachmentExtensions `json:"extensions,omitempty"`
+	Link       LinkResp             `json:"_links,omitempty"`
+	Expandable *ExpandableResponse  `json:"_expandable,omitempty"`
+}
+
+// 附件的附加信息
+type AttachmentMetadata struct {
+	MediaType string     `json:"mediaType,omitempty"`
+	Comment   string     `json:"comment,omitempty"`
+	Labels    *LabelList `json:"labels,omitempty"`
+}
+
+// 附件的扩展信息
+type AttachmentExtensions struct {
+	MediaType string `json:"mediaType,omitempty"`
+	FileSize  int64  `json:"fileSize,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+}
+
+// 附件的媒体类型
+func (att *Attachment) MediaType() string {
+	if att.Extensions.MediaType != "" {
+		return att.Extensions.MediaType
+	}
+	if att.Metadata != nil {
+		return att.Metadata.MediaType
+	}
+	return ""
+}
+
+// 附件的大小（字节）
+func (att *Attachment) FileSize() int64 {
+	return att.Extensions.FileSize
+}
+
+// 附件的备注
+func (att *Attachment) Comment() string {
+	if att.Extensions.Comment != "" {
+		return att.Extensions.Comment
+	}
+	if att.Metadata != nil {
+		return att.Metadata.Comment
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+package confluence
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-http/confluence/storage"
+)
+
+// 页面上保存内容哈希的属性Key
+const SyncHashPropertyKey = "go-http-confluence-sync-hash"
+
+// 保存在页面属性中的内容哈希
+type SyncHash struct {
+	Hash    string `json:"hash"`
+	Version int    `json:"version"` //保存哈希时的页面版本，页面被他人修改后哈希失效
+}
+
+// 规范化时忽略的属性，这些属性由Confluence在保存时自动生成
+var canonicalIgnoredAttrs = map[string]bool{
+	"ac:macro-id":       true,
+	"ac:schema-version": true,
+	"ac:local-id":       true,
+	"local-id":          true,
+}
+
+// 规范化时保留空白的元素
+var canonicalPreserveSpace = map[string]bool{
+	"ac:plain-text-body": true,
+	"pre":                true,
+}
+
+// 规范化时视为块级的元素，其边界处的空白会被去除
+var canonicalBlockElements = map[string]bool{
+	"root": true, "p": true, "div": true, "br": true, "hr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "blockquote": true, "pre": true,
+	"table": true, "colgroup": true, "col": true, "thead": true, "tbody": true, "tr": true, "th": true, "td": true,
+	"ac:structured-macro": true, "ac:parameter": true, "ac:rich-text-body": true, "ac:plain-text-body": true,
+	"ac:layout": true, "ac:layout-section": true, "ac:layout-cell": true,
+	"ac:task-list": true, "ac:task": true, "ac:task-id": true, "ac:task-status": true, "ac:task-body": true,
+}
+
+var (
+	canonicalSpaceRegexp   = regexp.MustCompile(`\s+`)
+	storageNewlineReplacer = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+)
+
+// 将Storage格式的内容规范化，使语义相同的内容得到相同的结果
+//
+// 规范化会统一空白、属性顺序、自闭合标签、实体和CDATA的写法，
+// 并忽略Confluence保存时自动添加的宏ID等属性
+func CanonicalizeStorage(body string) (string, error) {
+	doc, err := parseNormalizedStorage(body)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	writeCanonical(&sb, "root", textMergedChildren(doc), false)
+	return sb.String(), nil
+}
+
+// 计算Storage格式内容的哈希，内容无法解析时使用原始内容计算
+func StorageHash(body string) string {
+	canonical, err := CanonicalizeStorage(body)
+	if err != nil {
+		canonical = strings.TrimSpace(body)
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(sum[:])
+}
+
+// 本地规范化后对比的变化检测器，无需请求服务器
+//
+// 内容无法解析时，如设置了Fallback则交由其检测，否则视为有变化
+type LocalChangeDetector struct {
+	Fallback ChangeDetector
+}
+
+func (d LocalChangeDetector) Changed(oldStorage, newStorage string) (bool, error) {
+	oldValue, oldErr := CanonicalizeStorage(oldStorage)
+	newValue, newErr := CanonicalizeStorage(newStorage)
+	if oldErr != nil || newErr != nil {
+		if d.Fallback != nil {
+			return d.Fallback.Changed(oldStorage, newStorage)
+		}
+		return true, nil
+	}
+
+	return oldValue != newValue, nil
+}
+
+// 输出规范化后的一组子节点，parent为父元素名称
+func writeCanonical(sb *strings.Builder, parent string, nodes []*storage.Node, preserve bool) {
+	for i, node := range nodes {
+		if node.Type == storage.TextNode {
+			text := node.Data
+			if !preserve {
+				text = canonicalSpaceRegexp.ReplaceAllString(text, " ")
+				if i == 0 && canonicalBlockElements[parent] || i > 0 && canonicalBlockElements[nodes[i-1].Name] {
+					text = strings.TrimLeft(text, " ")
+				}
+				last := len(nodes) - 1
+				if i == last && canonicalBlockElements[parent] || i < last && canonicalBlockElements[nodes[i+1].Name] {
+					text = strings.TrimRight(text, " ")
+				}
+			}
+			xml.EscapeText(sb, []byte(text))
+			continue
+		}
+
+		sb.WriteString("<" + node.Name)
+
+		attrs := make([]storage.Attr, 0, len(node.Attrs))
+		for _, attr := range node.Attrs {
+			if !canonicalIgnoredAttrs[attr.Name] {
+				attrs = append(attrs, attr)
+			}
+		}
+		sort.Slice(attrs, func(i, j int) bool { return attrs[i].Name < attrs[j].Name })
+
+		//与Storage格式的转义不同，使用xml.EscapeText以保持已保存的哈希不变
+		for _, attr := range attrs {
+			sb.WriteString(" " + attr.Name + `="`)
+			xml.EscapeText(sb, []byte(attr.Value))
+			sb.WriteString(`"`)
+		}
+
+		//去除空白后没有内容的元素统一输出为自闭合标签
+		var inner strings.Builder
+		writeCanonical(&inner, node.Name, textMergedChildren(node), preserve || canonicalPreserveSpace[node.Name])
+
+		if inner.Len() == 0 {
+			sb.WriteString("/>")
+			continue
+		}
+
+		sb.WriteString(">" + inner.String() + "</" + node.Name + ">")
+	}
+}
+
+// 统一换行符后解析Storage格式的内容，用于只关心内容语义的转换
+func parseNormalizedStorage(body string) (*storage.Node, error) {
+	doc, err := storage.Parse(storageNewlineReplacer.Replace(body))
+	if err != nil {
+		return nil, fmt.Errorf("解析内容失败: %s", err)
+	}
+	return doc, nil
+}
+
+// 节点的子元素和文本，相邻的文本和CDATA合并为一个文本节点，注释等其他节点被忽略
+func textMergedChildren(node *storage.Node) []*storage.Node {
+	var nodes []*storage.Node
+	for _, child := range node.Children {
+		switch child.Type {
+		case storage.ElementNode:
+			nodes = append(nodes, child)
+		case storage.TextNode, storage.CDATANode:
+			if n := len(nodes); n > 0 && nodes[n-1].Type == storage.TextNode {
+				nodes[n-1].Data += child.Data
+			} else {
+				nodes = append(nodes, storage.NewText(child.Data))
+			}
+		}
+	}
+	return nodes
+}
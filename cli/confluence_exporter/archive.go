@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-http/confluence"
+)
+
+// 使用Confluence的空间导出功能导出整个空间，等待导出任务结束后下载归档文件到导出目录
+func (exp *exporter) exportSpaceArchive(exportType confluence.SpaceExportType) error {
+	os.MkdirAll(exp.outDir, 0755)
+
+	file := path.Join(exp.outDir, exp.space+".export")
+	opt := &confluence.WaitTaskOption{
+		OnProgress: func(task confluence.LongTask) {
+			log.Printf("[%3d%%] %s", task.PercentageComplete, task.Message())
+		},
+	}
+
+	downloadPath, err := exp.client.SpaceExportToFile(context.Background(), exp.space, exportType, file, opt)
+	if err != nil {
+		return err
+	}
+
+	//使用导出文件的原文件名
+	name := path.Base(strings.SplitN(downloadPath, "?", 2)[0])
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+
+	target := path.Join(exp.outDir, name)
+	err = os.Rename(file, target)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("导出完成: (%8.2f KiB) %s", kib64(info.Size()), target)
+	return nil
+}
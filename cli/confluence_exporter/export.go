@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-http/confluence"
+)
+
+// 空间导出器
+type exporter struct {
+	client   *confluence.Client
+	space    string
+	outDir   string
+	format   string
+	workers  int
+	failFast bool
+
+	dirByTitle map[string]string //页面标题到导出目录的映射，用于转换页面间的链接
+	dirById    map[string]string //页面ID到导出目录的映射
+
+	mu        sync.Mutex //保护以下字段
+	state     confluence.ExportState
+	stats     exportStats
+	failures  []exportFailure
+	stopped   bool
+	progress  int
+	downloads chan downloadJob
+}
+
+// 清空导出目录后导出空间的所有页面
+func (exp *exporter) exportAll() error {
+	pages, err := exp.client.AllSpaceContentsWithOpt(exp.space, confluence.ContentTypePage, confluence.ExpandOpt(exp.pageExpand()...))
+	if err != nil {
+		return err
+	}
+
+	//清空原目录
+	os.RemoveAll(exp.outDir)
+	os.MkdirAll(exp.outDir, 0755)
+
+	exp.state = confluence.ExportState{
+		Space: exp.space,
+		Pages: make(map[string]confluence.ExportPageState),
+	}
+
+	exp.indexPages(pages)
+
+	exp.runPool(pages, func(page confluence.Content) error {
+		exp.logProgress(page, len(pages))
+
+		err := exp.exportPage(page)
+		if err != nil {
+			return err
+		}
+
+		return exp.exportPageAttachments(page)
+	})
+
+	err = exp.finish(pages)
+	if err != nil {
+		return err
+	}
+
+	return exp.summary()
+}
+
+// 记录所有页面的导出目录
+func (exp *exporter) indexPages(pages []confluence.Content) {
+	exp.dirByTitle = make(map[string]string, len(pages))
+	exp.dirById = make(map[string]string, len(pages))
+	for _, page := range pages {
+		exp.dirByTitle[page.Title] = exp.pageDirOf(page)
+		exp.dirById[page.Id] = exp.pageDirOf(page)
+	}
+}
+
+// 页面内容的文件名
+func (exp *exporter) bodyFile() string {
+	switch exp.format {
+	case formatMarkdown:
+		return confluence.ExportMarkdownFile
+	case formatHTML:
+		return confluence.ExportHTMLFile
+	case formatPDF:
+		return confluence.ExportPDFFile
+	case formatWord:
+		return confluence.ExportWordFile
+	}
+	return confluence.ExportBodyFile
+}
+
+// 获取页面时展开的字段，HTML格式使用服务端渲染的export_view，PDF和Word格式单独下载文档
+func (exp *exporter) pageExpand() []confluence.Expander {
+	fields := []confluence.Expander{confluence.Expand.Version, confluence.Expand.Ancestors, confluence.Expand.Metadata.Labels}
+
+	switch exp.format {
+	case formatHTML:
+		fields = append(fields, confluence.Expand.Body.ExportView)
+	case formatPDF, formatWord:
+	default:
+		fields = append(fields, confluence.Expand.Body.Storage)
+	}
+
+	return fields
+}
+
+// 输出页面的处理进度
+func (exp *exporter) logProgress(page confluence.Content, total int) {
+	exp.mu.Lock()
+	exp.progress++
+	progress := exp.progress
+	exp.mu.Unlock()
+
+	log.Printf("[%3d/%3d] %s", progress, total, page.Title)
+}
+
+// 写入空间信息和导出状态，HTML格式同时生成站点首页、导航树和搜索索引
+func (exp *exporter) finish(pages []confluence.Content) error {
+	spaceManifest := confluence.SpaceManifest{
+		Key:        exp.space,
+		ExportedAt: time.Now().Format(time.RFC3339),
+		Pages:      len(pages),
+	}
+	if info, err := exp.client.SpaceByKey(exp.space); err == nil {
+		spaceManifest.Name = info.Name
+	}
+
+	err := writeJSON(path.Join(exp.outDir, confluence.ExportSpaceFile), spaceManifest)
+	if err != nil {
+		return err
+	}
+
+	if exp.format == formatHTML {
+		err = exp.writeSite(pages, spaceManifest.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	exp.state.ExportedAt = spaceManifest.ExportedAt
+	return writeJSON(path.Join(exp.outDir, confluence.ExportStateFile), exp.state)
+}
+
+// 导出页面的内容和清单，清单中的附件信息由exportAttachments补充
+func (exp *exporter) exportPage(page confluence.Content) error {
+	pageDir := exp.pageDirOf(page)
+	os.MkdirAll(pageDir, 0755)
+
+	//输出文件
+	body := page.Body.Storage.Value
+	switch exp.format {
+	case formatMarkdown:
+		var err error
+		body, err = confluence.StorageToMarkdown(body, exp.markdownOption(page))
+		if err != nil {
+			return fmt.Errorf("转换Markdown错误: %s", err)
+		}
+	case formatHTML:
+		var err error
+		body, err = exp.renderHTML(page)
+		if err != nil {
+			return err
+		}
+	case formatPDF, formatWord:
+		var buf bytes.Buffer
+		var err error
+		if exp.format == formatPDF {
+			_, err = exp.client.ContentExportPDF(page.Id, &buf)
+		} else {
+			_, err = exp.client.ContentExportWord(page.Id, &buf)
+		}
+		if err != nil {
+			return fmt.Errorf("导出文档错误: %s", err)
+		}
+		body = buf.String()
+	}
+
+	file := path.Join(pageDir, exp.bodyFile())
+	err := writeFile(file, []byte(body))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("          (%8.2f KiB) %s", kib(len(body)), file)
+
+	manifest := confluence.NewPageManifest(page)
+
+	//v2接口返回的页面不包含标签，需要单独获取
+	if page.Metadata == nil {
+		labels, err := exp.client.ContentLabels(page.Id)
+		if err != nil {
+			return fmt.Errorf("获取标签错误: %s", err)
+		}
+		for _, label := range labels {
+			manifest.Labels = append(manifest.Labels, label.Name)
+		}
+	}
+
+	properties, err := exp.client.ContentProperties(page.Id)
+	if err != nil {
+		return fmt.Errorf("获取属性错误: %s", err)
+	}
+	for _, prop := range properties {
+		if manifest.Properties == nil {
+			manifest.Properties = make(map[string]json.RawMessage)
+		}
+		manifest.Properties[prop.Key] = prop.Value
+	}
+
+	//保留原清单中的附件信息，由exportAttachments更新
+	var old confluence.PageManifest
+	if readJSON(path.Join(pageDir, confluence.ExportManifestFile), &old) == nil {
+		manifest.Attachments = old.Attachments
+	}
+
+	err = writeJSON(path.Join(pageDir, confluence.ExportManifestFile), manifest)
+	if err != nil {
+		return err
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	pageState := exp.state.Pages[page.Id]
+	pageState.Version = page.Version.Number
+	pageState.Dir = exp.relDir(pageDir)
+	exp.state.Pages[page.Id] = pageState
+	exp.stats.pages++
+
+	return nil
+}
+
+// 获取页面的附件列表并导出附件
+func (exp *exporter) exportPageAttachments(page confluence.Content) error {
+	attachments, err := exp.client.AttachmentsByContentId(page.Id)
+	if err != nil {
+		return fmt.Errorf("获取附件列表错误: %s", err)
+	}
+
+	return exp.exportAttachments(page, attachments)
+}
+
+// 导出页面的附件，已导出且版本未变的附件不重复下载，远端已删除的附件从本地删除
+//
+// 需要下载的附件交由附件worker并发下载，下载成功后才记录附件状态
+func (exp *exporter) exportAttachments(page confluence.Content, attachments []confluence.Attachment) error {
+	pageDir := exp.pageDirOf(page)
+
+	exp.mu.Lock()
+	oldAttachments := exp.state.Pages[page.Id].Attachments
+	exp.mu.Unlock()
+
+	changed := false
+	manifests := make([]confluence.AttachmentManifest, 0, len(attachments))
+	fileById := make(map[string]string)
+	current := make(map[string]confluence.ExportAttachmentState)
+	var jobs []downloadJob
+	for _, att := range attachments {
+		attManifest := confluence.NewAttachmentManifest(att)
+		manifests = append(manifests, attManifest)
+		fileById[att.Id] = attManifest.File
+
+		attachmentFile := path.Join(pageDir, attManifest.File)
+		attState := confluence.ExportAttachmentState{Version: att.Version.Number, File: attManifest.File}
+
+		old, found := oldAttachments[att.Id]
+		if found && old == attState && fileExists(attachmentFile) {
+			current[att.Id] = attState
+			continue
+		}
+
+		changed = true
+		jobs = append(jobs, downloadJob{
+			pageId:    page.Id,
+			pageTitle: page.Title,
+			att:       att,
+			file:      attachmentFile,
+			state:     attState,
+		})
+	}
+
+	//删除远端已删除或改名的附件
+	for id, old := range oldAttachments {
+		if fileById[id] == old.File {
+			continue
+		}
+
+		changed = true
+		os.Remove(path.Join(pageDir, old.File))
+		log.Printf("          (已删除) %s", path.Join(pageDir, old.File))
+	}
+
+	exp.mu.Lock()
+	pageState := exp.state.Pages[page.Id]
+	pageState.Attachments = current
+	exp.state.Pages[page.Id] = pageState
+	exp.mu.Unlock()
+
+	if changed || oldAttachments == nil {
+		//更新清单中的附件信息
+		manifestFile := path.Join(pageDir, confluence.ExportManifestFile)
+
+		var manifest confluence.PageManifest
+		err := readJSON(manifestFile, &manifest)
+		if err != nil {
+			return fmt.Errorf("读取%s错误: %s", manifestFile, err)
+		}
+		manifest.Attachments = manifests
+
+		err = writeJSON(manifestFile, manifest)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, job := range jobs {
+		exp.downloads <- job
+	}
+
+	return nil
+}
+
+// 页面对应的导出目录，由各级父页面的标题组成
+func (exp *exporter) pageDirOf(page confluence.Content) string {
+	pageDirs := []string{exp.outDir}
+	for _, ancestor := range page.Ancestors {
+		pageDirs = append(pageDirs, confluence.ExportFileName(ancestor.Title))
+	}
+	pageDirs = append(pageDirs, confluence.ExportFileName(page.Title))
+
+	return path.Join(pageDirs...)
+}
+
+// 相对于导出根目录的路径
+func (exp *exporter) relDir(dir string) string {
+	rel, err := filepath.Rel(exp.outDir, dir)
+	if err != nil {
+		return dir
+	}
+	return filepath.ToSlash(rel)
+}
+
+// 以缩进格式写入JSON文件
+func writeJSON(file string, data interface{}) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("编码%s错误: %s", file, err)
+	}
+
+	return writeFile(file, content)
+}
+
+// 写入文件
+func writeFile(file string, content []byte) error {
+	err := ioutil.WriteFile(file, content, 0644)
+	if err != nil {
+		return fmt.Errorf("写入%s错误: %s", file, err)
+	}
+
+	return nil
+}
+
+// 读取JSON文件
+func readJSON(file string, data interface{}) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, data)
+}
+
+// 文件是否存在
+func fileExists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
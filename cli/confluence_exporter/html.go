@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-http/confluence"
+)
+
+// 以HTML格式导出时站点的公共文件
+const (
+	staticDir           = "_static"
+	searchIndexJSONFile = "search-index.json"
+)
+
+var (
+	htmlURLAttrRegexp    = regexp.MustCompile(`(\s(?:href|src))="([^"]*)"`)
+	htmlPageIdRegexp     = regexp.MustCompile(`[?&]pageId=(\d+)`)
+	htmlCloudPageRegexp  = regexp.MustCompile(`/spaces/[^/]+/pages/(\d+)`)
+	htmlDisplayRegexp    = regexp.MustCompile(`/display/([^/?#]+)/([^?#]+)`)
+	htmlDownloadRegexp   = regexp.MustCompile(`/download/(?:attachments|thumbnails)/(\d+)/([^?#]+)`)
+	htmlTagRegexp        = regexp.MustCompile(`<[^>]*>`)
+	htmlWhitespaceRegexp = regexp.MustCompile(`\s+`)
+)
+
+// 页面内容在HTML文件中的起止标记，用于生成搜索索引
+//
+// 模板中的注释会被html/template去除，因此标记随页面内容一起输出
+const (
+	htmlContentBegin = "<!--content-->"
+	htmlContentEnd   = "<!--/content-->"
+)
+
+// HTML页面模板
+var htmlPageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Root}}_static/style.css">
+</head>
+<body data-root="{{.Root}}" data-page="{{.Path}}">
+<nav id="sidebar">
+<input id="search" type="search" placeholder="搜索">
+<ul id="search-results"></ul>
+<div id="tree"></div>
+</nav>
+<main>
+<div class="breadcrumbs">{{range .Breadcrumbs}}<a href="{{.Href}}">{{.Title}}</a> / {{end}}</div>
+<h1>{{.Title}}</h1>
+<div id="content">{{.Body}}</div>
+</main>
+<script src="{{.Root}}_static/tree.js"></script>
+<script src="{{.Root}}_static/search-index.js"></script>
+<script src="{{.Root}}_static/site.js"></script>
+</body>
+</html>
+`))
+
+// HTML页面模板的数据
+type htmlPage struct {
+	Title       string
+	Root        string //导出根目录的相对路径
+	Path        string //页面相对于导出根目录的路径
+	Breadcrumbs []htmlLink
+	Body        template.HTML
+}
+
+// HTML链接
+type htmlLink struct {
+	Title string
+	Href  string
+}
+
+// 导航树的节点
+type siteNode struct {
+	Title    string      `json:"title"`
+	Path     string      `json:"path"`
+	Children []*siteNode `json:"children,omitempty"`
+}
+
+// 搜索索引的条目
+type searchDocument struct {
+	Title string `json:"title"`
+	Path  string `json:"path"`
+	Text  string `json:"text"`
+}
+
+// 将页面的export_view渲染为HTML页面，并把站内链接和附件地址改写为本地文件
+func (exp *exporter) renderHTML(page confluence.Content) (string, error) {
+	if page.Body.ExportView == nil {
+		return "", fmt.Errorf("页面内容缺少export_view")
+	}
+
+	pageDir := exp.pageDirOf(page)
+	root := relLink(pageDir, exp.outDir) + "/"
+
+	data := htmlPage{
+		Title: page.Title,
+		Root:  root,
+		Path:  path.Join(exp.relDir(pageDir), confluence.ExportHTMLFile),
+		Body:  template.HTML(htmlContentBegin + exp.rewriteHTMLLinks(pageDir, page.Body.ExportView.Value) + htmlContentEnd),
+	}
+
+	for _, ancestor := range page.Ancestors {
+		href := ancestor.Title
+		if dir, found := exp.dirById[ancestor.Id]; found {
+			href = hrefOf(relLink(pageDir, path.Join(dir, confluence.ExportHTMLFile)))
+		}
+		data.Breadcrumbs = append(data.Breadcrumbs, htmlLink{Title: ancestor.Title, Href: href})
+	}
+
+	var buf bytes.Buffer
+	err := htmlPageTemplate.Execute(&buf, data)
+	if err != nil {
+		return "", fmt.Errorf("渲染HTML错误: %s", err)
+	}
+
+	return buf.String(), nil
+}
+
+// 改写HTML中href和src指向的地址
+func (exp *exporter) rewriteHTMLLinks(pageDir, body string) string {
+	return htmlURLAttrRegexp.ReplaceAllStringFunc(body, func(attr string) string {
+		m := htmlURLAttrRegexp.FindStringSubmatch(attr)
+		return m[1] + `="` + html.EscapeString(exp.rewriteURL(pageDir, html.UnescapeString(m[2]))) + `"`
+	})
+}
+
+// 改写地址：站内页面改写为相对的index.html路径，附件改写为本地文件，其他站内地址补全为绝对地址
+func (exp *exporter) rewriteURL(pageDir, link string) string {
+	if link == "" || strings.HasPrefix(link, "#") {
+		return link
+	}
+
+	site, err := url.Parse(exp.client.Hostname)
+	if err != nil {
+		return link
+	}
+	origin := site.Scheme + "://" + site.Host
+
+	//只处理站内地址
+	local := link
+	switch {
+	case strings.HasPrefix(link, origin+"/"):
+		local = strings.TrimPrefix(link, origin)
+	case strings.HasPrefix(link, "/") && !strings.HasPrefix(link, "//"):
+	default:
+		return link
+	}
+
+	fragment := ""
+	if k := strings.IndexByte(local, '#'); k >= 0 {
+		local, fragment = local[:k], local[k:]
+	}
+
+	if m := htmlDownloadRegexp.FindStringSubmatch(local); m != nil {
+		if dir, found := exp.dirById[m[1]]; found {
+			filename, err := url.PathUnescape(m[2])
+			if err == nil {
+				return hrefOf(relLink(pageDir, path.Join(dir, confluence.ExportAttachmentFileName(filename))))
+			}
+		}
+	}
+
+	pageId := ""
+	if m := htmlPageIdRegexp.FindStringSubmatch(local); m != nil {
+		pageId = m[1]
+	} else if m := htmlCloudPageRegexp.FindStringSubmatch(local); m != nil {
+		pageId = m[1]
+	}
+	if dir, found := exp.dirById[pageId]; found {
+		return hrefOf(relLink(pageDir, path.Join(dir, confluence.ExportHTMLFile))) + fragment
+	}
+
+	if m := htmlDisplayRegexp.FindStringSubmatch(local); m != nil && m[1] == exp.space {
+		title, err := url.PathUnescape(strings.Replace(m[2], "+", " ", -1))
+		if dir, found := exp.dirByTitle[title]; err == nil && found {
+			return hrefOf(relLink(pageDir, path.Join(dir, confluence.ExportHTMLFile))) + fragment
+		}
+	}
+
+	return origin + local + fragment
+}
+
+// 生成站点首页、导航树、搜索索引和静态文件
+//
+// 搜索索引从已导出的HTML文件中提取，增量导出时未变化的页面同样会被索引
+func (exp *exporter) writeSite(pages []confluence.Content, spaceName string) error {
+	staticPath := path.Join(exp.outDir, staticDir)
+	os.MkdirAll(staticPath, 0755)
+
+	//导航树
+	nodeById := make(map[string]*siteNode, len(pages))
+	for _, page := range pages {
+		nodeById[page.Id] = &siteNode{
+			Title: page.Title,
+			Path:  path.Join(exp.relDir(exp.pageDirOf(page)), confluence.ExportHTMLFile),
+		}
+	}
+
+	tree := make([]*siteNode, 0)
+	for _, page := range pages {
+		node := nodeById[page.Id]
+		if len(page.Ancestors) > 0 {
+			if parent, found := nodeById[page.Ancestors[len(page.Ancestors)-1].Id]; found {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		tree = append(tree, node)
+	}
+
+	//搜索索引
+	docs := make([]searchDocument, 0, len(pages))
+	for _, page := range pages {
+		node := nodeById[page.Id]
+		content, err := ioutil.ReadFile(path.Join(exp.outDir, node.Path))
+		if err != nil {
+			continue
+		}
+
+		docs = append(docs, searchDocument{
+			Title: page.Title,
+			Path:  node.Path,
+			Text:  htmlText(string(content)),
+		})
+	}
+
+	err := writeJSON(path.Join(exp.outDir, searchIndexJSONFile), docs)
+	if err != nil {
+		return err
+	}
+
+	//通过file://浏览时无法读取JSON文件，因此同时以脚本形式输出导航树和搜索索引
+	err = writeScript(path.Join(staticPath, "tree.js"), "siteTree", tree)
+	if err != nil {
+		return err
+	}
+
+	err = writeScript(path.Join(staticPath, "search-index.js"), "searchIndex", docs)
+	if err != nil {
+		return err
+	}
+
+	err = writeFile(path.Join(staticPath, "site.js"), []byte(siteScript))
+	if err != nil {
+		return err
+	}
+
+	err = writeFile(path.Join(staticPath, "style.css"), []byte(siteStyle))
+	if err != nil {
+		return err
+	}
+
+	//首页列出顶层页面
+	if spaceName == "" {
+		spaceName = exp.space
+	}
+
+	var body strings.Builder
+	body.WriteString("<ul>")
+	for _, node := range tree {
+		body.WriteString(`<li><a href="` + html.EscapeString(hrefOf(node.Path)) + `">` + html.EscapeString(node.Title) + "</a></li>")
+	}
+	body.WriteString("</ul>")
+
+	var buf bytes.Buffer
+	err = htmlPageTemplate.Execute(&buf, htmlPage{
+		Title: spaceName,
+		Body:  template.HTML(body.String()),
+	})
+	if err != nil {
+		return fmt.Errorf("渲染HTML错误: %s", err)
+	}
+
+	return writeFile(path.Join(exp.outDir, confluence.ExportHTMLFile), buf.Bytes())
+}
+
+// 相对路径转换为链接地址，对空格等字符进行转义
+func hrefOf(rel string) string {
+	return (&url.URL{Path: rel}).String()
+}
+
+// 提取HTML页面内容的纯文本
+func htmlText(page string) string {
+	begin := strings.Index(page, htmlContentBegin)
+	end := strings.LastIndex(page, htmlContentEnd)
+	if begin >= 0 && end > begin {
+		page = page[begin+len(htmlContentBegin) : end]
+	}
+
+	text := html.UnescapeString(htmlTagRegexp.ReplaceAllString(page, " "))
+	return strings.TrimSpace(htmlWhitespaceRegexp.ReplaceAllString(text, " "))
+}
+
+// 以全局变量的形式写入脚本文件
+func writeScript(file, name string, data interface{}) error {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("编码%s错误: %s", file, err)
+	}
+
+	return writeFile(file, []byte("var "+name+" = "+string(content)+";\n"))
+}
+
+// 站点脚本：渲染导航树并提供全文搜索
+const siteScript = `(function () {
+  var root = document.body.getAttribute("data-root");
+  var current = document.body.getAttribute("data-page");
+
+  function link(title, href) {
+    var a = document.createElement("a");
+    a.href = root + href;
+    a.textContent = title;
+    if (href === current) {
+      a.className = "current";
+    }
+    return a;
+  }
+
+  function build(nodes) {
+    var ul = document.createElement("ul");
+    nodes.forEach(function (node) {
+      var li = document.createElement("li");
+      li.appendChild(link(node.title, node.path));
+      if (node.children) {
+        li.appendChild(build(node.children));
+      }
+      ul.appendChild(li);
+    });
+    return ul;
+  }
+
+  document.getElementById("tree").appendChild(build(siteTree));
+
+  var input = document.getElementById("search");
+  var results = document.getElementById("search-results");
+  input.addEventListener("input", function () {
+    var words = input.value.toLowerCase().split(/\s+/).filter(Boolean);
+    results.innerHTML = "";
+    if (!words.length) {
+      return;
+    }
+
+    searchIndex.filter(function (doc) {
+      var text = (doc.title + " " + doc.text).toLowerCase();
+      return words.every(function (word) {
+        return text.indexOf(word) >= 0;
+      });
+    }).slice(0, 50).forEach(function (doc) {
+      var li = document.createElement("li");
+      li.appendChild(link(doc.title, doc.path));
+      results.appendChild(li);
+    });
+  });
+})();
+`
+
+// 站点样式
+const siteStyle = `body { margin: 0; display: flex; font-family: sans-serif; line-height: 1.5; }
+#sidebar { width: 280px; min-height: 100vh; padding: 16px; box-sizing: border-box; background: #f4f5f7; overflow: auto; }
+#sidebar ul { padding-left: 16px; }
+#sidebar a { color: #172b4d; text-decoration: none; }
+#sidebar a.current { font-weight: bold; }
+#search { width: 100%; box-sizing: border-box; padding: 4px; }
+main { flex: 1; padding: 16px 32px; min-width: 0; }
+.breadcrumbs { color: #6b778c; font-size: 0.9em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #c1c7d0; padding: 4px 8px; }
+pre { background: #f4f5f7; padding: 8px; overflow: auto; }
+img { max-width: 100%; }
+`
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-http/confluence"
+)
+
+// 根据上次导出的状态增量导出空间
+//
+// 先获取只包含版本和父页面的页面清单，与状态对比后：
+// 移动标题或位置变化的页面目录，删除远端已删除的页面，只下载版本变化的页面和附件
+func (exp *exporter) exportIncremental() error {
+	stateFile := path.Join(exp.outDir, confluence.ExportStateFile)
+	err := readJSON(stateFile, &exp.state)
+	if err != nil || exp.state.Space != exp.space {
+		log.Printf("没有可用的导出状态，执行全量导出")
+		return exp.exportAll()
+	}
+	if exp.state.Pages == nil {
+		exp.state.Pages = make(map[string]confluence.ExportPageState)
+	}
+
+	opt := confluence.ExpandOpt(confluence.Expand.Version, confluence.Expand.Ancestors)
+	pages, err := exp.client.AllSpaceContentsWithOpt(exp.space, confluence.ContentTypePage, opt)
+	if err != nil {
+		return err
+	}
+
+	//父页面先于子页面处理，移动父页面目录时子页面随之移动
+	sort.SliceStable(pages, func(i, j int) bool {
+		return len(pages[i].Ancestors) < len(pages[j].Ancestors)
+	})
+
+	exp.indexPages(pages)
+
+	remote := make(map[string]bool, len(pages))
+	for _, page := range pages {
+		remote[page.Id] = true
+
+		old, found := exp.state.Pages[page.Id]
+		newDir := exp.relDir(exp.pageDirOf(page))
+		if found && old.Dir != newDir {
+			exp.moveDir(page.Id, old.Dir, newDir)
+		}
+	}
+
+	removed := 0
+	for id, old := range exp.state.Pages {
+		if !remote[id] {
+			exp.removePage(old)
+			delete(exp.state.Pages, id)
+			removed++
+		}
+	}
+
+	exp.stats.removed = removed
+
+	exp.runPool(pages, func(page confluence.Content) error {
+		exp.mu.Lock()
+		old, found := exp.state.Pages[page.Id]
+		exp.mu.Unlock()
+
+		bodyFile := path.Join(exp.outDir, old.Dir, exp.bodyFile())
+		if found && old.Version == page.Version.Number && fileExists(bodyFile) {
+			exp.mu.Lock()
+			exp.stats.unchanged++
+			exp.mu.Unlock()
+		} else {
+			exp.logProgress(page, len(pages))
+
+			full, err := exp.client.ContentByIdWithOpt(page.Id, confluence.ExpandOpt(exp.pageExpand()...))
+			if err != nil {
+				return fmt.Errorf("获取内容错误: %s", err)
+			}
+
+			err = exp.exportPage(full)
+			if err != nil {
+				return err
+			}
+		}
+
+		return exp.exportPageAttachments(page)
+	})
+
+	err = exp.finish(pages)
+	if err != nil {
+		return err
+	}
+
+	return exp.summary()
+}
+
+// 移动页面目录，并更新该目录下所有页面的状态
+func (exp *exporter) moveDir(id, oldDir, newDir string) {
+	from := path.Join(exp.outDir, oldDir)
+	to := path.Join(exp.outDir, newDir)
+
+	os.MkdirAll(path.Dir(to), 0755)
+	err := os.Rename(from, to)
+	if err != nil {
+		//无法移动时重新导出该页面
+		log.Printf("移动%s到%s失败，将重新导出: %s", from, to, err)
+		pageState := exp.state.Pages[id]
+		pageState.Version = 0
+		pageState.Dir = newDir
+		exp.state.Pages[id] = pageState
+		return
+	}
+
+	log.Printf("          (已移动) %s -> %s", from, to)
+
+	for pageId, pageState := range exp.state.Pages {
+		if pageState.Dir == oldDir || strings.HasPrefix(pageState.Dir, oldDir+"/") {
+			pageState.Dir = newDir + strings.TrimPrefix(pageState.Dir, oldDir)
+			exp.state.Pages[pageId] = pageState
+		}
+	}
+}
+
+// 删除已导出页面的文件，目录中仍有其他内容（如子页面）时保留目录
+func (exp *exporter) removePage(pageState confluence.ExportPageState) {
+	dir := path.Join(exp.outDir, pageState.Dir)
+
+	os.Remove(path.Join(dir, exp.bodyFile()))
+	os.Remove(path.Join(dir, confluence.ExportManifestFile))
+	for _, att := range pageState.Attachments {
+		os.Remove(path.Join(dir, att.File))
+	}
+	os.Remove(dir)
+
+	log.Printf("          (已删除) %s", dir)
+}
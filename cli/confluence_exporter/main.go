@@ -2,89 +2,77 @@ package main
 
 import (
 	"flag"
-	"fmt"
-	"io/ioutil"
 	"log"
-	"os"
-	"path"
 
 	"github.com/go-http/confluence"
 )
 
+// 导出格式
+const (
+	formatXML      = "xml"
+	formatMarkdown = "markdown"
+	formatHTML     = "html"
+	formatPDF      = "pdf"
+	formatWord     = "word"
+)
+
+// 空间导出的类型
+var spaceExportTypes = map[string]confluence.SpaceExportType{
+	"xml":  confluence.SpaceExportXML,
+	"html": confluence.SpaceExportHTML,
+	"pdf":  confluence.SpaceExportPDF,
+}
+
 func main() {
-	var addr, user, pass, space, dir string
+	var addr, user, pass, space, dir, format, spaceExport string
+	var incremental, failFast bool
+	var workers int
 
 	flag.StringVar(&addr, "addr", "https://www.confluence.com", "Confluence访问地址")
 	flag.StringVar(&user, "u", "", "用户名")
 	flag.StringVar(&pass, "p", "", "密码")
 	flag.StringVar(&space, "s", "", "Confluence空间标识")
 	flag.StringVar(&dir, "d", "", "要导出的目录")
+	flag.StringVar(&format, "format", formatXML, "导出格式: xml为Storage格式，markdown为Markdown格式，html为可离线浏览的静态站点，pdf、word为服务端导出的文档")
+	flag.StringVar(&spaceExport, "space-export", "", "使用Confluence的空间导出功能导出整个空间的归档文件: xml、html或pdf")
+	flag.BoolVar(&incremental, "incremental", false, "增量导出：仅下载有变化的页面和附件，并删除远端已删除的内容")
+	flag.IntVar(&workers, "j", 4, "并发获取页面和下载附件的数量")
+	flag.BoolVar(&failFast, "fail-fast", false, "遇到第一个错误时停止导出")
 
 	flag.Parse()
 
-	err := exportSpaceTo(addr, user, pass, space, dir)
-	if err != nil {
-		log.Fatal(err)
+	switch format {
+	case formatXML, formatMarkdown, formatHTML, formatPDF, formatWord:
+	default:
+		log.Fatalf("不支持的导出格式: %s", format)
 	}
-}
-
-func exportSpaceTo(addr, user, pass, space, outDir string) error {
-	client := confluence.New(addr, user, pass)
 
-	pages, err := client.AllSpacePages(space)
-	if err != nil {
-		return err
+	if workers < 1 {
+		workers = 1
 	}
 
-	//清空原目录
-	os.RemoveAll(outDir)
-	os.MkdirAll(outDir, 0755)
-
-	total := len(pages)
-	for i, page := range pages {
-
-		//获取父页面信息
-		pageDirs := []string{outDir}
-		for _, ancestor := range page.Ancestors {
-			pageDirs = append(pageDirs, ancestor.Title)
-		}
-
-		//创建所需目录
-		pageDir := path.Join(pageDirs...)
-		os.MkdirAll(pageDir, 0755)
-
-		//将目录同名的文件挪为index.xml
-		os.Rename(pageDir+".xml", path.Join(pageDir, "index.xml"))
-
-		//输出文件
-		file := path.Join(pageDir, page.Title+".xml")
-		ioutil.WriteFile(file, []byte(page.Body.Storage.Value), 0755)
-
-		fileKBSize := float32(len(page.Body.Storage.Value)) / 100
-		log.Printf("[%3d/%3d] %s", i+1, total, page.Title)
-		log.Printf("          (%8.2f KiB) %s", fileKBSize, file)
-
-		//下载附件
-		attachmentDir := path.Join(pageDir, page.Title)
-		os.MkdirAll(attachmentDir, 0755)
-		os.Rename(attachmentDir+".xml", path.Join(attachmentDir, "index.xml")) //将目录同名文件挪为index.xml
-
-		attachments, err := client.AttachmentsByContentId(page.Id)
-		if err != nil {
-			return fmt.Errorf("获取%s附件列表错误: %s", page.Title, err)
-		}
-		for _, att := range attachments {
-			attachmentData, err := client.Download(att.Link.Download)
-			if err != nil {
-				return fmt.Errorf("下载%s附件%s错误: %s", page.Title, att.Title, err)
-			}
+	exp := &exporter{
+		client:   confluence.New(addr, user, pass),
+		space:    space,
+		outDir:   dir,
+		format:   format,
+		workers:  workers,
+		failFast: failFast,
+	}
 
-			attachmentKBSize := float32(len(attachmentData)) / 100
-			attachmentFile := path.Join(attachmentDir, att.Title)
-			ioutil.WriteFile(attachmentFile, attachmentData, 0755)
-			log.Printf("          (%8.2f KiB) %s", attachmentKBSize, attachmentFile)
+	var err error
+	if spaceExport != "" {
+		exportType, found := spaceExportTypes[spaceExport]
+		if !found {
+			log.Fatalf("不支持的空间导出类型: %s", spaceExport)
 		}
+		err = exp.exportSpaceArchive(exportType)
+	} else if incremental {
+		err = exp.exportIncremental()
+	} else {
+		err = exp.exportAll()
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
-
-	return nil
 }
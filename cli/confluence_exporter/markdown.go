@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+
+	"github.com/go-http/confluence"
+)
+
+// 页面转换为Markdown时的链接选项：页面链接转换为相对的index.md路径，附件链接转换为本地文件
+func (exp *exporter) markdownOption(page confluence.Content) *confluence.MarkdownOption {
+	pageDir := exp.pageDirOf(page)
+
+	return &confluence.MarkdownOption{
+		PageLink: func(spaceKey, title, anchor string) string {
+			href := ""
+			if dir, found := exp.dirByTitle[title]; found && (spaceKey == "" || spaceKey == exp.space) {
+				href = relLink(pageDir, path.Join(dir, confluence.ExportMarkdownFile))
+			} else {
+				//其他空间或不存在的页面链接到Confluence
+				if spaceKey == "" {
+					spaceKey = exp.space
+				}
+				href = exp.client.Hostname + "/display/" + url.PathEscape(spaceKey) + "/" + url.PathEscape(title)
+			}
+
+			if anchor != "" {
+				href += "#" + anchor
+			}
+			return href
+		},
+		AttachmentLink: func(pageTitle, filename string) string {
+			file := confluence.ExportAttachmentFileName(filename)
+			if pageTitle == "" {
+				return file
+			}
+
+			if dir, found := exp.dirByTitle[pageTitle]; found {
+				return relLink(pageDir, path.Join(dir, file))
+			}
+			return file
+		},
+	}
+}
+
+// 从目录fromDir指向文件target的相对链接
+func relLink(fromDir, target string) string {
+	rel, err := filepath.Rel(fromDir, target)
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
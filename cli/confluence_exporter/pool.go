@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/go-http/confluence"
+)
+
+// 附件下载任务
+type downloadJob struct {
+	pageId    string
+	pageTitle string
+	att       confluence.Attachment
+	file      string
+	state     confluence.ExportAttachmentState
+}
+
+// 导出失败的条目
+type exportFailure struct {
+	item string
+	err  error
+}
+
+// 导出统计
+type exportStats struct {
+	pages       int
+	unchanged   int
+	removed     int
+	attachments int
+	bytes       int64
+}
+
+// 使用页面和附件两组worker并发导出页面
+//
+// 页面worker执行job获取并写入页面，附件下载任务由附件worker并发执行，
+// 单个条目失败时记录错误并继续，开启failFast时停止处理剩余条目
+func (exp *exporter) runPool(pages []confluence.Content, job func(confluence.Content) error) {
+	exp.downloads = make(chan downloadJob)
+
+	var downloadWg sync.WaitGroup
+	for i := 0; i < exp.workers; i++ {
+		downloadWg.Add(1)
+		go func() {
+			defer downloadWg.Done()
+			for dl := range exp.downloads {
+				if exp.isStopped() {
+					continue
+				}
+				err := exp.download(dl)
+				if err != nil {
+					exp.fail(dl.pageTitle+"/"+dl.att.Title, err)
+				}
+			}
+		}()
+	}
+
+	jobs := make(chan confluence.Content)
+
+	var pageWg sync.WaitGroup
+	for i := 0; i < exp.workers; i++ {
+		pageWg.Add(1)
+		go func() {
+			defer pageWg.Done()
+			for page := range jobs {
+				if exp.isStopped() {
+					continue
+				}
+				err := job(page)
+				if err != nil {
+					exp.fail(page.Title, err)
+				}
+			}
+		}()
+	}
+
+	for _, page := range pages {
+		jobs <- page
+	}
+	close(jobs)
+	pageWg.Wait()
+
+	close(exp.downloads)
+	downloadWg.Wait()
+}
+
+// 下载附件，成功后记录附件状态
+func (exp *exporter) download(dl downloadJob) error {
+	data, err := exp.client.Download(dl.att.Link.Download)
+	if err != nil {
+		return fmt.Errorf("下载附件错误: %s", err)
+	}
+
+	err = writeFile(dl.file, data)
+	if err != nil {
+		return err
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	pageState := exp.state.Pages[dl.pageId]
+	if pageState.Attachments == nil {
+		pageState.Attachments = make(map[string]confluence.ExportAttachmentState)
+	}
+	pageState.Attachments[dl.att.Id] = dl.state
+	exp.state.Pages[dl.pageId] = pageState
+
+	exp.stats.attachments++
+	exp.stats.bytes += int64(len(data))
+
+	log.Printf("          (%8.2f KiB) %s", kib(len(data)), dl.file)
+
+	return nil
+}
+
+// 记录失败的条目
+func (exp *exporter) fail(item string, err error) {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	exp.failures = append(exp.failures, exportFailure{item: item, err: err})
+	log.Printf("导出%s失败: %s", item, err)
+
+	if exp.failFast {
+		exp.stopped = true
+	}
+}
+
+// 是否已因错误停止
+func (exp *exporter) isStopped() bool {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	return exp.stopped
+}
+
+// 输出导出汇总，存在失败条目时返回错误
+func (exp *exporter) summary() error {
+	log.Printf("导出完成: 页面%d, 未变化%d, 删除%d, 附件%d (%.2f KiB), 失败%d",
+		exp.stats.pages, exp.stats.unchanged, exp.stats.removed,
+		exp.stats.attachments, kib64(exp.stats.bytes), len(exp.failures))
+
+	for _, failure := range exp.failures {
+		log.Printf("  失败: %s: %s", failure.item, failure.err)
+	}
+
+	if len(exp.failures) > 0 {
+		return fmt.Errorf("导出过程中有%d项失败", len(exp.failures))
+	}
+
+	return nil
+}
+
+// 字节数转换为KiB
+func kib(size int) float64 {
+	return kib64(int64(size))
+}
+
+// 字节数转换为KiB
+func kib64(size int64) float64 {
+	return float64(size) / 1024
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-http/confluence"
+)
+
+func main() {
+	var addr, user, pass, space, dir, parentId, mapFile string
+
+	flag.StringVar(&addr, "addr", "https://www.confluence.com", "Confluence访问地址")
+	flag.StringVar(&user, "u", "", "用户名")
+	flag.StringVar(&pass, "p", "", "密码")
+	flag.StringVar(&space, "s", "", "导入到的Confluence空间标识")
+	flag.StringVar(&dir, "d", "", "confluence_exporter导出的目录")
+	flag.StringVar(&parentId, "parent", "", "导入到的父页面ID，为空时导入到空间顶层")
+	flag.StringVar(&mapFile, "map", "", "输出原页面ID到新页面ID映射的JSON文件")
+
+	flag.Parse()
+
+	imp := &importer{
+		client: confluence.New(addr, user, pass),
+		space:  space,
+		idMap:  make(map[string]string),
+	}
+
+	//读取原空间信息，用于替换内容中对原空间的引用
+	var spaceManifest confluence.SpaceManifest
+	if data, err := ioutil.ReadFile(path.Join(dir, confluence.ExportSpaceFile)); err == nil {
+		json.Unmarshal(data, &spaceManifest)
+	}
+	imp.sourceSpace = spaceManifest.Key
+
+	err := imp.importDir(dir, parentId)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if mapFile != "" {
+		data, _ := json.MarshalIndent(imp.idMap, "", "  ")
+		err = ioutil.WriteFile(mapFile, data, 0644)
+		if err != nil {
+			log.Fatalf("写入%s错误: %s", mapFile, err)
+		}
+	}
+
+	log.Printf("导入完成: 页面%d, 附件%d, 失败%d", len(imp.idMap), imp.attachments, imp.failures)
+	if imp.failures > 0 {
+		os.Exit(1)
+	}
+}
+
+// 导入器，将confluence_exporter导出的目录还原到指定空间
+type importer struct {
+	client      *confluence.Client
+	space       string
+	sourceSpace string
+
+	idMap       map[string]string //原页面ID到新页面ID的映射
+	attachments int
+	failures    int
+}
+
+// 待导入的页面
+type pageDir struct {
+	dir      string
+	manifest confluence.PageManifest
+}
+
+// 按原有顺序导入目录下的所有页面
+func (imp *importer) importDir(dir, parentId string) error {
+	pages, err := readPageDirs(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, page := range pages {
+		content, err := imp.importPage(page, parentId)
+		if err != nil {
+			imp.failures++
+			log.Printf("导入%s失败: %s", page.manifest.Title, err)
+			continue
+		}
+
+		err = imp.importDir(page.dir, content.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 导入单个页面及其标签、属性和附件
+func (imp *importer) importPage(page pageDir, parentId string) (confluence.Content, error) {
+	manifest := page.manifest
+
+	data, err := ioutil.ReadFile(path.Join(page.dir, confluence.ExportBodyFile))
+	if err != nil && !os.IsNotExist(err) {
+		return confluence.Content{}, fmt.Errorf("读取页面内容错误: %s", err)
+	}
+
+	body := string(data)
+	if imp.sourceSpace != "" && imp.sourceSpace != imp.space {
+		body = strings.Replace(body, `ri:space-key="`+imp.sourceSpace+`"`, `ri:space-key="`+imp.space+`"`, -1)
+	}
+
+	options := &confluence.DrawModifyPageOption{
+		Space:    imp.space,
+		Title:    manifest.Title,
+		ParentId: parentId,
+		Data:     body,
+	}
+
+	pageSync := confluence.NewPageSync(imp.client)
+	pageSync.Placement = confluence.AnywherePlacement{}
+
+	result, err := pageSync.Sync(options)
+	if err != nil {
+		return confluence.Content{}, err
+	}
+	content := result.Content
+
+	log.Printf("[%-9s] %s", result.Action, manifest.Title)
+	if manifest.Id != "" {
+		imp.idMap[manifest.Id] = content.Id
+	}
+
+	if len(manifest.Labels) > 0 {
+		_, err = imp.client.ContentLabelsAdd(content.Id, manifest.Labels...)
+		if err != nil {
+			return content, fmt.Errorf("添加标签错误: %s", err)
+		}
+	}
+
+	for key, value := range manifest.Properties {
+		//内容哈希与原页面的版本相关，导入后不再有效
+		if key == confluence.SyncHashPropertyKey {
+			continue
+		}
+
+		_, err = imp.client.ContentPropertySet(content.Id, key, value)
+		if err != nil {
+			return content, fmt.Errorf("设置属性%s错误: %s", key, err)
+		}
+	}
+
+	var files []string
+	for _, att := range manifest.Attachments {
+		files = append(files, path.Join(page.dir, att.File))
+	}
+	if len(files) > 0 {
+		err = imp.client.UpdateContentAttachments(content.Id, files)
+		if err != nil {
+			return content, fmt.Errorf("上传附件错误: %s", err)
+		}
+		imp.attachments += len(files)
+	}
+
+	return content, nil
+}
+
+// 读取目录下的页面目录，按原页面的位置和标题排序
+func readPageDirs(dir string) ([]pageDir, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取目录%s错误: %s", dir, err)
+	}
+
+	var pages []pageDir
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+
+		page := pageDir{dir: path.Join(dir, info.Name())}
+
+		data, err := ioutil.ReadFile(path.Join(page.dir, confluence.ExportManifestFile))
+		if err != nil {
+			//没有清单文件的目录不是页面目录
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("读取%s清单错误: %s", page.dir, err)
+		}
+
+		err = json.Unmarshal(data, &page.manifest)
+		if err != nil {
+			return nil, fmt.Errorf("解析%s清单错误: %s", page.dir, err)
+		}
+
+		pages = append(pages, page)
+	}
+
+	//没有位置信息的页面排在最后
+	sort.SliceStable(pages, func(i, j int) bool {
+		pi, pj := pages[i].manifest.Position, pages[j].manifest.Position
+		if pi != pj && (pi < 0 || pj < 0) {
+			return pj < 0
+		}
+		if pi != pj {
+			return pi < pj
+		}
+		return pages[i].manifest.Title < pages[j].manifest.Title
+	})
+
+	return pages, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/go-http/confluence"
+)
+
+// 页面标题变更的参数，格式为"旧标题=新标题"，可以指定多次
+type renameFlags []confluence.TitleChange
+
+func (f *renameFlags) String() string {
+	var list []string
+	for _, change := range *f {
+		list = append(list, change.OldTitle+"="+change.NewTitle)
+	}
+	return strings.Join(list, ",")
+}
+
+func (f *renameFlags) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("格式错误，应为\"旧标题=新标题\": %s", value)
+	}
+
+	*f = append(*f, confluence.TitleChange{OldTitle: value[:i], NewTitle: value[i+1:]})
+	return nil
+}
+
+func main() {
+	var addr, user, pass, space, pageId, title string
+	var external, dryRun, fullScan bool
+	var renames renameFlags
+
+	flag.StringVar(&addr, "addr", "https://www.confluence.com", "Confluence访问地址")
+	flag.StringVar(&user, "u", "", "用户名")
+	flag.StringVar(&pass, "p", "", "密码")
+	flag.StringVar(&space, "s", "", "Confluence空间标识")
+	flag.BoolVar(&external, "external", false, "同时检查外部链接")
+	flag.Var(&renames, "rename", "将对旧标题页面的引用改为新标题，格式为\"旧标题=新标题\"，可以指定多次")
+	flag.StringVar(&pageId, "page", "", "要改名的页面ID，与-title一起使用，改名后修改其他页面对该页面的引用")
+	flag.StringVar(&title, "title", "", "页面的新标题")
+	flag.BoolVar(&fullScan, "full-scan", false, "改名时扫描空间所有页面和博客查找引用，而不是通过CQL搜索")
+	flag.BoolVar(&dryRun, "dry-run", false, "只显示需要修改的页面，不实际修改")
+
+	flag.Parse()
+
+	if (pageId == "") != (title == "") {
+		log.Fatal("-page和-title需要一起使用")
+	}
+
+	client := confluence.New(addr, user, pass)
+
+	if pageId != "" {
+		opt := confluence.RenameOption{DryRun: dryRun, FullScan: fullScan}
+		if space != "" {
+			opt.Spaces = []string{space}
+		}
+
+		report, err := client.RenamePage(pageId, title, opt)
+		for _, result := range report.Backlinks {
+			log.Printf("%s:%s(%s): 修改了%d处引用", result.SpaceKey, result.PageTitle, result.PageId, result.Changes)
+		}
+		for _, result := range report.Failed {
+			log.Printf("%s:%s(%s): 跳过: %s", result.SpaceKey, result.PageTitle, result.PageId, result.Error)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("改名完成: %s -> %s, 改名%v, 引用页面%d个, 跳过%d个", report.OldTitle, report.NewTitle, report.Renamed, len(report.Backlinks), len(report.Failed))
+		if len(report.Failed) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(renames) > 0 {
+		for i := range renames {
+			renames[i].SpaceKey = space
+		}
+
+		results, err := client.RewriteSpaceLinks(space, renames, dryRun)
+		failures := 0
+		for _, result := range results {
+			if result.Error != "" {
+				failures++
+				log.Printf("%s(%s): 修改失败: %s", result.PageTitle, result.PageId, result.Error)
+			} else {
+				log.Printf("%s(%s): 修改了%d处引用", result.PageTitle, result.PageId, result.Changes)
+			}
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("修改完成: 共%d个页面, 失败%d个", len(results)-failures, failures)
+		if failures > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	checker := confluence.NewLinkChecker(client, space)
+	checker.External = external
+
+	broken, err := checker.Check()
+	for _, link := range broken {
+		log.Print(link)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("检查完成: 无效链接%d个", len(broken))
+	if len(broken) > 0 {
+		os.Exit(1)
+	}
+}
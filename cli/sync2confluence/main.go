@@ -0,0 +1,324 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-http/confluence"
+	"github.com/go-http/confluence/storage"
+)
+
+func main() {
+	var addr, user, pass, space, dir, parentId, prefix, gitName, gitUrl string
+	var footerTemplate string
+	var validate, useGit bool
+	var commits int
+
+	flag.StringVar(&addr, "addr", "https://www.confluence.com", "Confluence访问地址")
+	flag.StringVar(&user, "u", "", "用户名")
+	flag.StringVar(&pass, "p", "", "密码")
+	flag.StringVar(&space, "s", "", "Confluence空间标识")
+	flag.StringVar(&dir, "d", "", "要同步的目录")
+	flag.StringVar(&parentId, "parent", "", "同步到的父页面ID，为空时同步到空间顶层")
+	flag.StringVar(&prefix, "prefix", "", "已存在的同名页面必须位于该路径下才会被更新，如/文档")
+	flag.StringVar(&gitName, "git-name", "", "仓库名称，用于页面末尾的修改历史")
+	flag.StringVar(&gitUrl, "git-url", "", "仓库地址，设置后在页面末尾添加修改历史")
+	flag.BoolVar(&useGit, "git", false, "从同步目录所在的git仓库读取文件的修改历史，未设置仓库地址时使用origin的地址")
+	flag.IntVar(&commits, "commits", 5, "开启-git时页面末尾显示的最近提交数量")
+	flag.StringVar(&footerTemplate, "footer-template", "", "修改历史内容的模板文件（html/template格式，数据为confluence.Provenance）")
+	flag.BoolVar(&validate, "validate", false, "发布前校验页面内容的格式和页面链接，有错误的页面不会被修改")
+
+	flag.Parse()
+
+	s := &syncer{
+		client:  confluence.New(addr, user, pass),
+		space:   space,
+		root:    dir,
+		prefix:  prefix,
+		gitName: gitName,
+		gitUrl:  strings.TrimSuffix(gitUrl, "/"),
+		counts:  make(map[confluence.SyncAction]int),
+	}
+
+	if validate {
+		titles, err := collectTitles(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		//本次同步的页面可能还未创建，链接到这些页面时不检查是否存在
+		s.validator = s.client.StorageValidator(space)
+		s.validator.Planned = func(spaceKey, title string) bool {
+			return (spaceKey == "" || spaceKey == space) && titles[title]
+		}
+	}
+
+	if useGit {
+		repo, err := confluence.OpenGitRepo(dir)
+		if err != nil {
+			log.Fatalf("打开git仓库失败: %s", err)
+		}
+		if s.gitUrl != "" {
+			repo.WebUrl = s.gitUrl
+			repo.Kind = confluence.GitHostKindOf(s.gitUrl)
+		}
+		s.repo = repo
+		s.commits = commits
+	}
+
+	s.footer = confluence.NewProvenanceFooter()
+	if footerTemplate != "" {
+		tpl, err := template.ParseFiles(footerTemplate)
+		if err != nil {
+			log.Fatalf("解析页脚模板失败: %s", err)
+		}
+		s.footer.Template = tpl
+	}
+
+	err := s.syncDir(dir, parentId)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("同步完成: 新建%d, 更新%d, 移动%d, 未变化%d, 上传附件%d, 失败%d",
+		s.counts[confluence.SyncCreated], s.counts[confluence.SyncUpdated], s.counts[confluence.SyncMoved],
+		s.counts[confluence.SyncUnchanged], s.attachments, s.failures)
+
+	if s.failures > 0 {
+		os.Exit(1)
+	}
+}
+
+// 目录同步器
+//
+// 目录结构与confluence_exporter的导出结果一致：
+// 每个页面对应一个目录，目录下的index.xml为页面内容，子目录为子页面，
+// 导出清单以外的其他文件（包括draw.io等导出的xml文件）为页面附件
+type syncer struct {
+	client  *confluence.Client
+	space   string
+	root    string
+	prefix  string
+	gitName string
+	gitUrl  string
+
+	validator *storage.Validator           //发布前的校验器，为空时不校验
+	footer    *confluence.ProvenanceFooter //设置仓库地址时在页面末尾添加的修改历史
+	repo      *confluence.GitRepo          //读取修改历史的git仓库，为空时不读取
+	commits   int                          //读取的最近提交数量
+
+	counts      map[confluence.SyncAction]int
+	attachments int
+	failures    int
+}
+
+// 待同步的页面
+type pageEntry struct {
+	title    string
+	bodyFile string //页面内容文件，为空表示空页面
+	dir      string //页面对应的目录，为空表示没有子页面和附件
+}
+
+// 同步目录下的所有页面到指定父页面下，目录中的附件上传到父页面
+func (s *syncer) syncDir(dir, parentId string) error {
+	entries, files, err := scanDir(dir)
+	if err != nil {
+		return err
+	}
+
+	if len(files) > 0 {
+		if parentId == "" {
+			log.Printf("忽略%s下的%d个附件: 没有对应的页面", dir, len(files))
+		} else {
+			s.uploadAttachments(parentId, dir, files)
+		}
+	}
+
+	for _, entry := range entries {
+		content, err := s.syncPage(entry, parentId)
+		if err != nil {
+			s.failures++
+			log.Printf("同步%s失败: %s", entry.title, err)
+			continue
+		}
+
+		if entry.dir == "" {
+			continue
+		}
+
+		err = s.syncDir(entry.dir, content.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 同步单个页面
+func (s *syncer) syncPage(entry pageEntry, parentId string) (confluence.Content, error) {
+	var data []byte
+	if entry.bodyFile != "" {
+		var err error
+		data, err = ioutil.ReadFile(entry.bodyFile)
+		if err != nil {
+			return confluence.Content{}, fmt.Errorf("读取%s错误: %s", entry.bodyFile, err)
+		}
+	}
+
+	options := &confluence.DrawModifyPageOption{
+		Space:               s.space,
+		Title:               entry.title,
+		ParentId:            parentId,
+		ConfluenceDirPrefix: s.prefix,
+		Data:                string(data),
+		GitName:             s.gitName,
+		GitUrl:              s.gitUrl,
+	}
+
+	pageSync := confluence.NewPageSync(s.client)
+	pageSync.Validator = s.validator
+	if s.repo != nil && entry.bodyFile != "" {
+		err := s.repo.FillOption(options, entry.bodyFile, s.commits)
+		if err != nil {
+			return confluence.Content{}, fmt.Errorf("读取%s的修改历史失败: %s", entry.bodyFile, err)
+		}
+		pageSync.Footer = s.footer
+	} else if s.gitUrl != "" && entry.bodyFile != "" {
+		rel, _ := filepath.Rel(s.root, entry.bodyFile)
+		options.FileName = filepath.ToSlash(rel)
+		options.FileUrl = s.gitUrl + "/" + options.FileName
+		pageSync.Footer = s.footer
+	}
+
+	result, err := pageSync.Sync(options)
+	if err != nil {
+		return result.Content, err
+	}
+
+	//校验的警告不影响发布
+	for _, diag := range result.Diagnostics {
+		log.Printf("%s:%s", entry.bodyFile, diag)
+	}
+	for _, warning := range result.Warnings {
+		log.Printf("%s: %s", entry.title, warning)
+	}
+
+	s.counts[result.Action]++
+	log.Printf("[%-9s] %s", result.Action, entry.title)
+
+	return result.Content, nil
+}
+
+// 上传页面附件
+func (s *syncer) uploadAttachments(contentId, dir string, files []string) {
+	if len(files) == 0 {
+		return
+	}
+
+	//附件内容未变化时不重复上传
+	for _, file := range files {
+		_, uploaded, err := s.client.AttachmentUploadIfChanged(contentId, file, "")
+		if err != nil {
+			s.failures++
+			log.Printf("上传%s下的附件失败: %s", dir, err)
+			continue
+		}
+
+		if uploaded {
+			s.attachments++
+		}
+	}
+}
+
+// 收集目录及其子目录中所有页面的标题
+func collectTitles(dir string) (map[string]bool, error) {
+	titles := make(map[string]bool)
+
+	var walk func(string) error
+	walk = func(dir string) error {
+		entries, _, err := scanDir(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			titles[entry.title] = true
+			if entry.dir != "" {
+				err = walk(entry.dir)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	err := walk(dir)
+	if err != nil {
+		return nil, err
+	}
+	return titles, nil
+}
+
+// 扫描目录，返回目录下的页面和附件
+func scanDir(dir string) ([]pageEntry, []string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取目录%s错误: %s", dir, err)
+	}
+
+	entryByTitle := make(map[string]*pageEntry)
+	entryOf := func(title string) *pageEntry {
+		if entryByTitle[title] == nil {
+			entryByTitle[title] = &pageEntry{title: title}
+		}
+		return entryByTitle[title]
+	}
+
+	var files []string
+	for _, info := range infos {
+		name := info.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		file := path.Join(dir, name)
+		switch {
+		case info.IsDir():
+			entry := entryOf(name)
+			entry.dir = file
+
+			//目录下的index.xml为页面内容，其他文件为附件，在同步该目录时上传
+			body := path.Join(file, confluence.ExportBodyFile)
+			if _, err := os.Stat(body); err == nil {
+				entry.bodyFile = body
+			}
+		case name == confluence.ExportBodyFile || name == confluence.ExportManifestFile || name == confluence.ExportSpaceFile:
+			//index.xml为所在目录对应页面的内容，json文件为导出时生成的清单
+		default:
+			files = append(files, file)
+		}
+	}
+
+	titles := make([]string, 0, len(entryByTitle))
+	for title := range entryByTitle {
+		titles = append(titles, title)
+	}
+	sort.Strings(titles)
+
+	entries := make([]pageEntry, 0, len(titles))
+	for _, title := range titles {
+		entries = append(entries, *entryByTitle[title])
+	}
+
+	return entries, files, nil
+}
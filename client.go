@@ -1,7 +1,9 @@
 package confluence
 
-import(
+import (
 	"strings"
+	"sync"
+	"time"
 )
 
 //Confluence客户端，封装了Confluence的常见资源操作
@@ -9,6 +11,14 @@ type Client struct {
 	Hostname string
 	Username string
 	Password string
+
+	//使用的REST接口版本，缺省根据服务器信息自动选择
+	ApiVersion int
+
+	serverInfo     *ServerInfo
+	serverInfoErr  error     //最近一次获取服务器信息的错误
+	serverInfoAt   time.Time //最近一次获取失败的时间，间隔serverInfoRetry后重新获取
+	serverInfoLock sync.Mutex
 }
 
 //创建新的Confluence客户端
@@ -1,6 +1,8 @@
 package confluence
 
 //Confluence的备注宏，用于备注git的信息
+//
+// Deprecated: 使用NoteMacro构造备注宏
 const ConfluenceNoteMacro = `
 <br/>
 <br/>
@@ -15,6 +17,9 @@ const ConfluenceNoteMacro = `
 </ac:structured-macro>
 `
 
+// 包含最近提交信息的备注宏模板
+//
+// Deprecated: 使用GetConfluenceNoteMacro或NoteMacro构造备注宏
 const NewConfluenceNoteMacro = `
 <br/>
 <br/>
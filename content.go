@@ -1,15 +1,29 @@
 package confluence
 
+import (
+	"encoding/json"
+	"strconv"
+)
+
 //Confluence内容
 type Content struct {
-	Id        string      `json:"id,omitempty"`
-	Type      string      `json:"type,omitempty"`
-	Title     string      `json:"title,omitempty"`
-	Space     Space       `json:"space,omitempty"`
-	Body      ContentBody `json:"body,omitempty"`
-	Link      LinkResp    `json:"_links",omitempty`
-	Version   Version     `json:"version,omitempty"`
-	Ancestors []Content   `json:"ancestors,omitempty"`
+	Id           string               `json:"id,omitempty"`
+	Type         string               `json:"type,omitempty"`
+	Status       string               `json:"status,omitempty"`
+	Title        string               `json:"title,omitempty"`
+	Space        Space                `json:"space,omitempty"`
+	History      *ContentHistory      `json:"history,omitempty"`
+	Body         ContentBody          `json:"body,omitempty"`
+	Link         LinkResp             `json:"_links,omitempty"`
+	Version      Version              `json:"version,omitempty"`
+	Ancestors    []Content            `json:"ancestors,omitempty"`
+	Children     *ContentChildren     `json:"children,omitempty"`
+	Descendants  *ContentChildren     `json:"descendants,omitempty"`
+	Container    *Content             `json:"container,omitempty"`
+	Metadata     *ContentMetadata     `json:"metadata,omitempty"`
+	Restrictions *ContentRestrictions `json:"restrictions,omitempty"`
+	Extensions   *ContentExtensions   `json:"extensions,omitempty"`
+	Expandable   *ExpandableResponse  `json:"_expandable,omitempty"`
 }
 
 const (
@@ -17,14 +31,20 @@ const (
 	ContentTypeBlog = "blog" //博客类型的Content
 )
 
+const (
+	ContentStatusCurrent = "current" //当前版本的内容
+	ContentStatusTrashed = "trashed" //回收站中的内容
+	ContentStatusDraft   = "draft"   //草稿
+)
+
 //Confluence内容体
 type ContentBody struct {
-	Storage             ContentBodyStorage `json:"storage,omitempty"`
-	Editor              interface{}        `json:"editor,omitempty"`
-	View                interface{}        `json:"view,omitempty"`
-	ExportView          interface{}        `json:"export_view,omitempty"`
-	StyledView          interface{}        `json:"styled_view,omitempty"`
-	AnonymousExportView interface{}        `json:"anonymous_export_view,omitempty"`
+	Storage             ContentBodyStorage  `json:"storage,omitempty"`
+	Editor              *ContentBodyStorage `json:"editor,omitempty"`
+	View                *ContentBodyStorage `json:"view,omitempty"`
+	ExportView          *ContentBodyStorage `json:"export_view,omitempty"`
+	StyledView          *ContentBodyStorage `json:"styled_view,omitempty"`
+	AnonymousExportView *ContentBodyStorage `json:"anonymous_export_view,omitempty"`
 }
 
 //Storage类型的内容体
@@ -33,8 +53,110 @@ type ContentBodyStorage struct {
 	Representation string `json:"representation,omitempty"`
 }
 
+// 内容的历史信息
+type ContentHistory struct {
+	Latest          bool     `json:"latest,omitempty"`
+	CreatedBy       *User    `json:"createdBy,omitempty"`
+	CreatedDate     string   `json:"createdDate,omitempty"`
+	LastUpdated     *Version `json:"lastUpdated,omitempty"`
+	PreviousVersion *Version `json:"previousVersion,omitempty"`
+	NextVersion     *Version `json:"nextVersion,omitempty"`
+}
+
+// 内容的子内容，按类型分组
+type ContentChildren struct {
+	Page       *ContentList `json:"page,omitempty"`
+	Comment    *ContentList `json:"comment,omitempty"`
+	Attachment *ContentList `json:"attachment,omitempty"`
+}
+
+// 分页的内容列表
+type ContentList struct {
+	PageResp
+	Results []Content `json:"results,omitempty"`
+}
+
+// 内容的附加信息
+type ContentMetadata struct {
+	Labels     *LabelList                 `json:"labels,omitempty"`
+	Properties map[string]ContentProperty `json:"properties,omitempty"`
+}
+
+// 分页的标签列表
+type LabelList struct {
+	PageResp
+	Results []Label `json:"results,omitempty"`
+}
+
+// 内容属性，用于在内容上保存任意的JSON数据
+type ContentProperty struct {
+	Id      string          `json:"id,omitempty"`
+	Key     string          `json:"key,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+	Version *Version        `json:"version,omitempty"`
+}
+
+// 内容的访问限制，按操作（read/update）分组
+type ContentRestrictions map[string]ContentRestriction
+
+// 某一操作的访问限制
+type ContentRestriction struct {
+	Operation    string `json:"operation,omitempty"`
+	Restrictions struct {
+		User struct {
+			PageResp
+			Results []User `json:"results,omitempty"`
+		} `json:"user,omitempty"`
+		Group struct {
+			PageResp
+			Results []struct {
+				Type string `json:"type,omitempty"`
+				Name string `json:"name,omitempty"`
+			} `json:"results,omitempty"`
+		} `json:"group,omitempty"`
+	} `json:"restrictions,omitempty"`
+}
+
+// 内容的扩展信息，附件包含媒体类型和大小，页面包含排序位置
+type ContentExtensions struct {
+	MediaType string      `json:"mediaType,omitempty"`
+	FileSize  int64       `json:"fileSize,omitempty"`
+	Comment   string      `json:"comment,omitempty"`
+	Position  interface{} `json:"position,omitempty"`
+}
+
 //设置Storage类型的内容体
 func (content *Content) SetStorageBody(value string) {
 	content.Body.Storage.Representation = "storage"
 	content.Body.Storage.Value = value
 }
+
+// 内容的标签名称
+func (content *Content) LabelNames() []string {
+	if content.Metadata == nil || content.Metadata.Labels == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(content.Metadata.Labels.Results))
+	for _, label := range content.Metadata.Labels.Results {
+		names = append(names, label.Name)
+	}
+	return names
+}
+
+// 页面在同级页面中的排序位置，未设置时返回-1
+func (content *Content) Position() int {
+	if content.Extensions == nil {
+		return -1
+	}
+
+	switch pos := content.Extensions.Position.(type) {
+	case float64:
+		return int(pos)
+	case string:
+		if n, err := strconv.Atoi(pos); err == nil {
+			return n
+		}
+	}
+	return -1
+}
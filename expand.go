@@ -0,0 +1,141 @@
+package confluence
+
+import (
+	"net/url"
+	"strings"
+)
+
+// 内容可展开的字段，用于构造请求的expand参数
+type Expansion string
+
+// 可以转换为展开字段的类型，包括Expansion和包含子字段的分组
+type Expander interface {
+	expansion() Expansion
+}
+
+func (e Expansion) expansion() Expansion {
+	return e
+}
+
+// 指定名称的子字段
+func (e Expansion) Child(name string) Expansion {
+	return e + "." + Expansion(name)
+}
+
+// 内容体的展开字段
+type BodyExpansion struct {
+	Expansion
+	Storage             Expansion
+	View                Expansion
+	ExportView          Expansion
+	StyledView          Expansion
+	Editor              Expansion
+	AnonymousExportView Expansion
+}
+
+// 子内容的展开字段
+type ChildrenExpansion struct {
+	Expansion
+	Page       Expansion
+	Comment    Expansion
+	Attachment Expansion
+}
+
+// 附加信息的展开字段
+type MetadataExpansion struct {
+	Expansion
+	Labels     Expansion
+	Properties Expansion
+}
+
+// 指定Key的内容属性
+func (e MetadataExpansion) Property(key string) Expansion {
+	return e.Properties.Child(key)
+}
+
+// 历史信息的展开字段
+type HistoryExpansion struct {
+	Expansion
+	LastUpdated     Expansion
+	PreviousVersion Expansion
+	NextVersion     Expansion
+	Contributors    Expansion
+}
+
+// 访问限制的展开字段
+type RestrictionsExpansion struct {
+	Expansion
+	Read   Expansion
+	Update Expansion
+}
+
+// 内容所有可展开的字段，如Expand.Body.Storage、Expand.Version
+var Expand = struct {
+	Version      Expansion
+	Space        Expansion
+	Ancestors    Expansion
+	Container    Expansion
+	Body         BodyExpansion
+	Children     ChildrenExpansion
+	Descendants  ChildrenExpansion
+	Metadata     MetadataExpansion
+	History      HistoryExpansion
+	Restrictions RestrictionsExpansion
+}{
+	Version:   "version",
+	Space:     "space",
+	Ancestors: "ancestors",
+	Container: "container",
+	Body: BodyExpansion{
+		Expansion:           "body",
+		Storage:             "body.storage",
+		View:                "body.view",
+		ExportView:          "body.export_view",
+		StyledView:          "body.styled_view",
+		Editor:              "body.editor",
+		AnonymousExportView: "body.anonymous_export_view",
+	},
+	Children: ChildrenExpansion{
+		Expansion:  "children",
+		Page:       "children.page",
+		Comment:    "children.comment",
+		Attachment: "children.attachment",
+	},
+	Descendants: ChildrenExpansion{
+		Expansion:  "descendants",
+		Page:       "descendants.page",
+		Comment:    "descendants.comment",
+		Attachment: "descendants.attachment",
+	},
+	Metadata: MetadataExpansion{
+		Expansion:  "metadata",
+		Labels:     "metadata.labels",
+		Properties: "metadata.properties",
+	},
+	History: HistoryExpansion{
+		Expansion:       "history",
+		LastUpdated:     "history.lastUpdated",
+		PreviousVersion: "history.previousVersion",
+		NextVersion:     "history.nextVersion",
+		Contributors:    "history.contributors",
+	},
+	Restrictions: RestrictionsExpansion{
+		Expansion: "restrictions",
+		Read:      "restrictions.read.restrictions.user,restrictions.read.restrictions.group",
+		Update:    "restrictions.update.restrictions.user,restrictions.update.restrictions.group",
+	},
+}
+
+// 生成包含指定展开字段的请求选项，可用于ContentByIdWithOpt等方法
+func ExpandOpt(fields ...Expander) url.Values {
+	return url.Values{"expand": {expandString(fields...)}}
+}
+
+// 拼接展开字段
+func expandString(fields ...Expander) string {
+	list := make([]string, 0, len(fields))
+	for _, field := range fields {
+		list = append(list, string(field.expansion()))
+	}
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,131 @@
+package confluence
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// 导出目录中的文件名
+//
+// 每个页面导出为一个目录，目录下包含页面内容、页面清单、附件和子页面目录，
+// 空间的信息保存在导出根目录下
+const (
+	ExportBodyFile     = "index.xml"  //页面的Storage格式内容
+	ExportMarkdownFile = "index.md"   //以Markdown格式导出时的页面内容
+	ExportHTMLFile     = "index.html" //以HTML格式导出时的页面内容
+	ExportPDFFile      = "index.pdf"  //以PDF格式导出时的页面内容
+	ExportWordFile     = "index.doc"  //以Word格式导出时的页面内容
+	ExportManifestFile = "page.json"  //页面清单
+	ExportSpaceFile    = "space.json"
+)
+
+// 导出的空间信息
+type SpaceManifest struct {
+	Key        string `json:"key"`
+	Name       string `json:"name,omitempty"`
+	ExportedAt string `json:"exportedAt,omitempty"`
+	Pages      int    `json:"pages"`
+}
+
+// 导出的页面清单，用于在导入时还原页面的层级、顺序、标签、属性和附件
+type PageManifest struct {
+	Id          string                     `json:"id"`
+	Type        string                     `json:"type,omitempty"`
+	Status      string                     `json:"status,omitempty"`
+	Title       string                     `json:"title"`
+	Space       string                     `json:"space,omitempty"`
+	Version     int                        `json:"version,omitempty"`
+	ParentId    string                     `json:"parentId,omitempty"`
+	ParentTitle string                     `json:"parentTitle,omitempty"`
+	Position    int                        `json:"position"`
+	Labels      []string                   `json:"labels,omitempty"`
+	Properties  map[string]json.RawMessage `json:"properties,omitempty"`
+	Attachments []AttachmentManifest       `json:"attachments,omitempty"`
+}
+
+// 导出的附件信息
+type AttachmentManifest struct {
+	Id        string `json:"id"`
+	Title     string `json:"title"`
+	File      string `json:"file"` //附件在页面目录中的文件名
+	MediaType string `json:"mediaType,omitempty"`
+	FileSize  int64  `json:"fileSize,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+	Version   int    `json:"version,omitempty"`
+}
+
+// 根据页面内容生成页面清单，不包含属性和附件
+func NewPageManifest(page Content) PageManifest {
+	manifest := PageManifest{
+		Id:       page.Id,
+		Type:     page.Type,
+		Status:   page.Status,
+		Title:    page.Title,
+		Space:    page.Space.Key,
+		Version:  page.Version.Number,
+		Position: page.Position(),
+		Labels:   page.LabelNames(),
+	}
+
+	if n := len(page.Ancestors); n > 0 {
+		manifest.ParentId = page.Ancestors[n-1].Id
+		manifest.ParentTitle = page.Ancestors[n-1].Title
+	}
+
+	return manifest
+}
+
+// 根据附件生成附件清单
+func NewAttachmentManifest(att Attachment) AttachmentManifest {
+	return AttachmentManifest{
+		Id:        att.Id,
+		Title:     att.Title,
+		File:      ExportAttachmentFileName(att.Title),
+		MediaType: att.MediaType(),
+		FileSize:  att.FileSize(),
+		Comment:   att.Comment(),
+		Version:   att.Version.Number,
+	}
+}
+
+// 附件的导出文件名，附件文件不能与页面内容和清单文件重名
+func ExportAttachmentFileName(title string) string {
+	file := ExportFileName(title)
+	switch file {
+	case ExportBodyFile, ExportMarkdownFile, ExportHTMLFile, ExportPDFFile, ExportWordFile, ExportManifestFile:
+		file = "_" + file
+	}
+	return file
+}
+
+// 标题对应的导出文件名，去除路径分隔符
+func ExportFileName(title string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(title)
+	if name == "" || name == "." || name == ".." {
+		name = "_" + name
+	}
+	return name
+}
+
+// 增量导出的状态文件，保存在导出根目录下
+const ExportStateFile = ".export-state.json"
+
+// 增量导出的状态，记录上次导出时各页面和附件的版本
+type ExportState struct {
+	Space      string                     `json:"space"`
+	ExportedAt string                     `json:"exportedAt,omitempty"`
+	Pages      map[string]ExportPageState `json:"pages"`
+}
+
+// 已导出页面的状态
+type ExportPageState struct {
+	Version     int                              `json:"version"`
+	Dir         string                           `json:"dir"` //页面目录，相对于导出根目录
+	Attachments map[string]ExportAttachmentState `json:"attachments,omitempty"`
+}
+
+// 已导出附件的状态
+type ExportAttachmentState struct {
+	Version int    `json:"version"`
+	File    string `json:"file"`
+}
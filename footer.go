@@ -0,0 +1,272 @@
+package confluence
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/go-http/confluence/storage"
+)
+
+const (
+	// 溯源块的缺省ID
+	ProvenanceBlockId = "go-http-confluence"
+	// 标识溯源块的宏参数，参数值为溯源块的ID
+	ProvenanceIdParam = "provenance-id"
+	// 保存溯源数据的内容属性
+	ProvenancePropertyKey = "go-http-confluence-provenance"
+)
+
+// 页面的溯源数据：页面内容来自哪个仓库的哪个文件，以及最近的提交
+type Provenance struct {
+	Commits  []Commit `json:"commits,omitempty"`
+	GitName  string   `json:"gitName,omitempty"`
+	GitUrl   string   `json:"gitUrl,omitempty"`
+	FileName string   `json:"fileName,omitempty"`
+	FileUrl  string   `json:"fileUrl,omitempty"`
+	SyncedAt string   `json:"syncedAt,omitempty"`
+}
+
+// 从同步选项中获取溯源数据
+func ProvenanceOf(options *DrawModifyPageOption) Provenance {
+	return Provenance{
+		Commits:  options.CommitList,
+		GitName:  options.GitName,
+		GitUrl:   options.GitUrl,
+		FileName: options.FileName,
+		FileUrl:  options.FileUrl,
+		SyncedAt: time.Now().Format(time.RFC3339),
+	}
+}
+
+// 缺省的溯源块内容模板
+var DefaultProvenanceTemplate = template.Must(template.New("provenance").Parse(
+	`<p>{{range .Commits}}<a href="{{.Href}}">{{.CommitId}}: </a>{{.CommitInfo}}<br/>{{end}}</p>` +
+		`{{if .GitUrl}}<p>渲染自<a href="{{.GitUrl}}">{{.GitName}}</a>仓库的<a href="{{.FileUrl}}">{{.FileName}}</a>文件</p>{{end}}`))
+
+// 溯源页脚：以带有ID参数的宏标识页面末尾的溯源块，溯源数据同时保存在内容属性中
+//
+// 通过宏参数识别溯源块，不依赖页脚前后的空白和分隔符，页面被编辑或重新序列化后仍能准确替换
+type ProvenanceFooter struct {
+	Id          string             //溯源块的ID，为空时使用ProvenanceBlockId
+	Macro       PanelType          //包裹溯源块的面板类型，为空时使用note
+	Title       string             //面板标题，为空时使用"修改历史"
+	Template    *template.Template //渲染面板内容的模板，数据为Provenance，为空时使用DefaultProvenanceTemplate
+	PropertyKey string             //保存溯源数据的内容属性，为空时不保存
+}
+
+// 创建使用缺省设置的溯源页脚
+func NewProvenanceFooter() *ProvenanceFooter {
+	return &ProvenanceFooter{PropertyKey: ProvenancePropertyKey}
+}
+
+// 溯源块的ID
+func (f *ProvenanceFooter) id() string {
+	if f.Id == "" {
+		return ProvenanceBlockId
+	}
+	return f.Id
+}
+
+// 渲染溯源块
+func (f *ProvenanceFooter) Render(options *DrawModifyPageOption) (string, error) {
+	return f.RenderProvenance(ProvenanceOf(options))
+}
+
+// 渲染指定数据的溯源块
+func (f *ProvenanceFooter) RenderProvenance(data Provenance) (string, error) {
+	tpl := f.Template
+	if tpl == nil {
+		tpl = DefaultProvenanceTemplate
+	}
+
+	var body bytes.Buffer
+	err := tpl.Execute(&body, data)
+	if err != nil {
+		return "", fmt.Errorf("渲染溯源块失败: %s", err)
+	}
+
+	macroType := f.Macro
+	if macroType == "" {
+		macroType = PanelNote
+	}
+	title := f.Title
+	if title == "" {
+		title = "修改历史"
+	}
+
+	macro := StructuredMacro{Name: string(macroType), BodyType: MacroRichTextBody, Body: body.String()}
+	macro.Param("title", title).Param(ProvenanceIdParam, f.id())
+	return macro.Storage(), nil
+}
+
+// 去除所有溯源块，以及旧版本以ConfluenceNoteSplite分隔的页脚
+func (f *ProvenanceFooter) Strip(storage string) string {
+	return RemoveProvenance(storage, f.id())
+}
+
+// 页面同步后在内容属性中保存溯源数据
+func (f *ProvenanceFooter) Saved(cli *Client, content Content, options *DrawModifyPageOption) error {
+	if f.PropertyKey == "" {
+		return nil
+	}
+
+	_, err := cli.ContentPropertySet(content.Id, f.PropertyKey, ProvenanceOf(options))
+	if err != nil {
+		return fmt.Errorf("保存溯源数据失败: %s", err)
+	}
+	return nil
+}
+
+// 查找指定ID的溯源块
+func findProvenance(doc *storage.Node, id string) []storage.Macro {
+	var blocks []storage.Macro
+	for _, macro := range doc.Macros("") {
+		if macro.Param(ProvenanceIdParam) == id {
+			blocks = append(blocks, macro)
+		}
+	}
+	return blocks
+}
+
+// 去除内容中指定ID的溯源块，以及旧版本以ConfluenceNoteSplite分隔的页脚
+//
+// 内容无法解析时只按ConfluenceNoteSplite去除
+func RemoveProvenance(content, id string) string {
+	content = stripNoteFooter(content)
+
+	doc, err := storage.Parse(content)
+	if err != nil {
+		return content
+	}
+
+	blocks := findProvenance(doc, id)
+	if len(blocks) == 0 {
+		return content
+	}
+
+	for _, block := range blocks {
+		block.Remove()
+	}
+	return strings.TrimRight(doc.String(), " \t\r\n")
+}
+
+// 替换内容中指定ID的溯源块：第一个溯源块替换为block，其余的删除，没有溯源块时追加到末尾
+func ReplaceProvenance(content, id, block string) (string, error) {
+	content = stripNoteFooter(content)
+
+	doc, err := storage.Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("解析页面内容失败: %s", err)
+	}
+
+	nodes, err := storage.ParseFragment(block)
+	if err != nil {
+		return "", fmt.Errorf("解析溯源块失败: %s", err)
+	}
+
+	blocks := findProvenance(doc, id)
+	if len(blocks) == 0 {
+		doc.AppendChild(nodes...)
+		return doc.String(), nil
+	}
+
+	blocks[0].ReplaceWith(nodes...)
+	for _, extra := range blocks[1:] {
+		extra.Remove()
+	}
+	return doc.String(), nil
+}
+
+// 读取页面内容属性中保存的溯源数据，没有溯源数据时返回false
+func (cli *Client) ProvenanceByContentId(contentId, key string) (Provenance, bool, error) {
+	prop, err := cli.ContentPropertyByKey(contentId, key)
+	if err != nil {
+		return Provenance{}, false, err
+	}
+	if prop.Key == "" {
+		return Provenance{}, false, nil
+	}
+
+	var data Provenance
+	err = json.Unmarshal(prop.Value, &data)
+	if err != nil {
+		return Provenance{}, false, fmt.Errorf("解析溯源数据失败: %s", err)
+	}
+
+	return data, true, nil
+}
+
+// 只更新页面的溯源块和溯源数据，不修改页面的其他内容
+func (cli *Client) ProvenanceUpdate(contentId string, footer *ProvenanceFooter, data Provenance) (Content, error) {
+	content, err := cli.ContentByIdWithOpt(contentId, ExpandOpt(Expand.Body.Storage, Expand.Version))
+	if err != nil {
+		return Content{}, err
+	}
+
+	block, err := footer.RenderProvenance(data)
+	if err != nil {
+		return Content{}, err
+	}
+
+	body, err := ReplaceProvenance(content.Body.Storage.Value, footer.id(), block)
+	if err != nil {
+		return Content{}, err
+	}
+
+	content, err = cli.updateStorageBody(content, body, "更新溯源信息")
+	if err != nil {
+		return Content{}, err
+	}
+
+	if footer.PropertyKey != "" {
+		_, err = cli.ContentPropertySet(contentId, footer.PropertyKey, data)
+		if err != nil {
+			return content, fmt.Errorf("保存溯源数据失败: %s", err)
+		}
+	}
+
+	return content, nil
+}
+
+// 删除页面的溯源块和溯源数据，页面没有溯源块时不更新页面内容
+func (cli *Client) ProvenanceRemove(contentId string, footer *ProvenanceFooter) (Content, error) {
+	content, err := cli.ContentByIdWithOpt(contentId, ExpandOpt(Expand.Body.Storage, Expand.Version))
+	if err != nil {
+		return Content{}, err
+	}
+
+	body := footer.Strip(content.Body.Storage.Value)
+	if body != content.Body.Storage.Value {
+		content, err = cli.updateStorageBody(content, body, "删除溯源信息")
+		if err != nil {
+			return Content{}, err
+		}
+	}
+
+	if footer.PropertyKey != "" {
+		prop, err := cli.ContentPropertyByKey(contentId, footer.PropertyKey)
+		if err != nil {
+			return content, err
+		}
+		if prop.Key != "" {
+			err = cli.ContentPropertyDelete(contentId, footer.PropertyKey)
+			if err != nil {
+				return content, fmt.Errorf("删除溯源数据失败: %s", err)
+			}
+		}
+	}
+
+	return content, nil
+}
+
+// 以新版本更新页面的Storage内容
+func (cli *Client) updateStorageBody(content Content, body, message string) (Content, error) {
+	content.Version.Number += 1
+	content.Version.Message = message
+	content.SetStorageBody(body)
+	return cli.ContentUpdate(content)
+}
@@ -0,0 +1,121 @@
+package confluence
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-http/confluence/storage"
+)
+
+func testProvenanceBlock(t *testing.T, footer *ProvenanceFooter, fileName string) string {
+	t.Helper()
+	block, err := footer.RenderProvenance(Provenance{
+		Commits:  []Commit{{CommitId: "abc", Href: "http://git/abc", CommitInfo: "a & b"}},
+		GitName:  "repo",
+		GitUrl:   "http://git/repo",
+		FileName: fileName,
+		FileUrl:  "http://git/repo/" + fileName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+// 渲染的溯源块可以通过缺省规格的校验
+func TestProvenanceRenderValid(t *testing.T) {
+	for _, macro := range []PanelType{"", PanelInfo, PanelWarning, PanelTip} {
+		footer := &ProvenanceFooter{Macro: macro}
+		block := testProvenanceBlock(t, footer, "a.md")
+
+		if diags := storage.Validate(block); len(diags) != 0 {
+			t.Errorf("%s面板的溯源块校验结果为%v", macro, diags)
+		}
+		if !strings.Contains(block, `<ac:parameter ac:name="provenance-id">`+ProvenanceBlockId+`</ac:parameter>`) {
+			t.Errorf("溯源块没有ID参数: %s", block)
+		}
+	}
+}
+
+func TestReplaceProvenance(t *testing.T) {
+	footer := NewProvenanceFooter()
+	block := testProvenanceBlock(t, footer, "new.md")
+	oldBlock := testProvenanceBlock(t, footer, "old.md")
+	otherBlock := testProvenanceBlock(t, &ProvenanceFooter{Id: "other"}, "other.md")
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "没有溯源块时追加到末尾",
+			content: `<p>body</p>`,
+			want:    `<p>body</p>` + block,
+		},
+		{
+			name:    "替换溯源块并保留其后的内容",
+			content: `<p>body</p>` + oldBlock + `<p>after</p>`,
+			want:    `<p>body</p>` + block + `<p>after</p>`,
+		},
+		{
+			name:    "删除多余的溯源块",
+			content: oldBlock + `<p>body</p>` + oldBlock,
+			want:    block + `<p>body</p>`,
+		},
+		{
+			name:    "不修改其他ID的溯源块",
+			content: `<p>body</p>` + otherBlock + oldBlock,
+			want:    `<p>body</p>` + otherBlock + block,
+		},
+		{
+			name:    "去除旧版本的页脚",
+			content: `<p>body</p>` + ConfluenceNoteSplite + `<p>old footer</p>`,
+			want:    `<p>body</p>` + block,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReplaceProvenance(tt.content, footer.id(), block)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("替换结果为\n%s\n期望\n%s", got, tt.want)
+			}
+		})
+	}
+
+	_, err := ReplaceProvenance(`<p>body`, footer.id(), block)
+	if err == nil {
+		t.Error("页面内容无法解析时应返回错误")
+	}
+}
+
+func TestRemoveProvenance(t *testing.T) {
+	footer := NewProvenanceFooter()
+	block := testProvenanceBlock(t, footer, "a.md")
+	otherBlock := testProvenanceBlock(t, &ProvenanceFooter{Id: "other"}, "other.md")
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"没有溯源块", `<p>body</p>`, `<p>body</p>`},
+		{"末尾的溯源块", "<p>body</p>\n" + block + "\n", `<p>body</p>`},
+		{"多个溯源块", block + `<p>body</p>` + block, `<p>body</p>`},
+		{"其他ID的溯源块", `<p>body</p>` + otherBlock + block, `<p>body</p>` + otherBlock},
+		{"旧版本的页脚", `<p>body</p>` + ConfluenceNoteSplite + `<p>old footer</p>`, `<p>body</p>`},
+		{"无法解析的内容", `<p>body` + ConfluenceNoteSplite + `<p>old`, `<p>body`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := footer.Strip(tt.content); got != tt.want {
+				t.Errorf("去除结果为\n%s\n期望\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package confluence
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// 代码托管平台的类型，决定提交和文件链接的格式
+type GitHostKind string
+
+const (
+	GitHub    GitHostKind = "github"    // {web}/commit/{hash}, {web}/blob/{ref}/{file}
+	GitLab    GitHostKind = "gitlab"    // {web}/-/commit/{hash}, {web}/-/blob/{ref}/{file}
+	Gitea     GitHostKind = "gitea"     // {web}/commit/{hash}, {web}/src/commit/{ref}/{file}
+	Bitbucket GitHostKind = "bitbucket" // {web}/commits/{hash}, {web}/src/{ref}/{file}
+)
+
+// 根据仓库地址的域名推断代码托管平台，无法推断时按GitHub格式处理
+func GitHostKindOf(webUrl string) GitHostKind {
+	u, err := url.Parse(webUrl)
+	if err != nil {
+		return GitHub
+	}
+
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case strings.Contains(host, "gitlab"):
+		return GitLab
+	case strings.Contains(host, "gitea"), strings.Contains(host, "codeberg"), strings.Contains(host, "forgejo"):
+		return Gitea
+	case strings.Contains(host, "bitbucket"):
+		return Bitbucket
+	}
+	return GitHub
+}
+
+// 本地git仓库，通过git命令读取文件的修改历史
+type GitRepo struct {
+	Root   string      //仓库的根目录
+	WebUrl string      //仓库的网页地址，由origin远程地址推导，可修改
+	Name   string      //仓库名称，缺省为网页地址的最后一段
+	Kind   GitHostKind //代码托管平台，缺省由网页地址推断
+	Ref    string      //文件链接使用的分支或提交，缺省为当前分支，分离HEAD时为当前提交
+}
+
+// 打开dir所在的git仓库
+func OpenGitRepo(dir string) (*GitRepo, error) {
+	root, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &GitRepo{Root: root}
+
+	//没有origin时不生成链接，仍然可以读取提交记录
+	remote, err := runGit(root, "remote", "get-url", "origin")
+	if err == nil {
+		repo.WebUrl = GitWebUrl(remote)
+		repo.Kind = GitHostKindOf(repo.WebUrl)
+		repo.Name = path.Base(repo.WebUrl)
+	} else {
+		repo.Name = filepath.Base(root)
+	}
+
+	ref, err := runGit(root, "rev-parse", "--abbrev-ref", "HEAD")
+	if err == nil && ref != "HEAD" {
+		repo.Ref = ref
+	} else if ref, err = runGit(root, "rev-parse", "HEAD"); err == nil {
+		repo.Ref = ref
+	}
+
+	return repo, nil
+}
+
+// 将git远程地址转换为仓库的网页地址
+//
+// 支持https://host/a/b.git、git@host:a/b.git、ssh://git@host:22/a/b.git等格式，
+// 地址中的用户名和密码会被去掉
+func GitWebUrl(remote string) string {
+	remote = strings.TrimSpace(remote)
+	remote = strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+
+	//scp格式: git@host:owner/repo
+	if !strings.Contains(remote, "://") {
+		if i := strings.Index(remote, ":"); i > 0 {
+			host := remote[:i]
+			if j := strings.LastIndex(host, "@"); j >= 0 {
+				host = host[j+1:]
+			}
+			return "https://" + host + "/" + strings.TrimPrefix(remote[i+1:], "/")
+		}
+		return remote
+	}
+
+	u, err := url.Parse(remote)
+	if err != nil {
+		return remote
+	}
+
+	scheme := u.Scheme
+	if scheme != "http" {
+		scheme = "https"
+	}
+
+	//ssh端口与网页端口无关
+	host := u.Host
+	if scheme == "https" && u.Scheme != "https" {
+		host = u.Hostname()
+	}
+
+	return scheme + "://" + host + strings.TrimSuffix(u.Path, "/")
+}
+
+// 提交的网页地址
+func (r *GitRepo) CommitUrl(hash string) string {
+	if r.WebUrl == "" {
+		return ""
+	}
+
+	switch r.kind() {
+	case GitLab:
+		return r.WebUrl + "/-/commit/" + hash
+	case Bitbucket:
+		return r.WebUrl + "/commits/" + hash
+	}
+	return r.WebUrl + "/commit/" + hash
+}
+
+// 文件的网页地址，file为仓库内的相对路径
+func (r *GitRepo) FileUrl(file string) string {
+	if r.WebUrl == "" || r.Ref == "" {
+		return ""
+	}
+
+	p := escapeGitPath(r.Ref) + "/" + escapeGitPath(file)
+	switch r.kind() {
+	case GitLab:
+		return r.WebUrl + "/-/blob/" + p
+	case Gitea:
+		if isCommitHash(r.Ref) {
+			return r.WebUrl + "/src/commit/" + p
+		}
+		return r.WebUrl + "/src/branch/" + p
+	case Bitbucket:
+		return r.WebUrl + "/src/" + p
+	}
+	return r.WebUrl + "/blob/" + p
+}
+
+// 文件在仓库内的相对路径，使用/分隔
+func (r *GitRepo) RelPath(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+
+	//仓库根目录由git给出，已解析符号链接
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+
+	rel, err := filepath.Rel(r.Root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s不在仓库%s中", file, r.Root)
+	}
+
+	return filepath.ToSlash(rel), nil
+}
+
+// 获取修改过指定文件的最近n个提交，n<=0时获取全部提交
+func (r *GitRepo) FileCommits(file string, n int) ([]Commit, error) {
+	rel, err := r.RelPath(file)
+	if err != nil {
+		return nil, err
+	}
+
+	//使用不可见的分隔符，避免与提交信息的内容冲突
+	args := []string{"log", "--no-color", "--format=%H%x1f%h%x1f%s%x1e"}
+	if n > 0 {
+		args = append(args, fmt.Sprintf("-n%d", n))
+	}
+	args = append(args, "--", rel)
+
+	out, err := runGit(r.Root, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		if len(fields) != 3 {
+			continue
+		}
+
+		commits = append(commits, Commit{
+			CommitId:   fields[1],
+			Href:       r.CommitUrl(fields[0]),
+			CommitInfo: fields[2],
+		})
+	}
+
+	return commits, nil
+}
+
+// 根据文件的修改历史填充同步选项中的提交记录、仓库和文件信息，已设置的仓库名称和地址保持不变
+func (r *GitRepo) FillOption(options *DrawModifyPageOption, file string, n int) error {
+	rel, err := r.RelPath(file)
+	if err != nil {
+		return err
+	}
+
+	commits, err := r.FileCommits(file, n)
+	if err != nil {
+		return err
+	}
+
+	options.CommitList = commits
+	options.FileName = rel
+	options.FileUrl = r.FileUrl(rel)
+	if options.GitName == "" {
+		options.GitName = r.Name
+	}
+	if options.GitUrl == "" {
+		options.GitUrl = r.WebUrl
+	}
+
+	return nil
+}
+
+func (r *GitRepo) kind() GitHostKind {
+	if r.Kind != "" {
+		return r.Kind
+	}
+	return GitHostKindOf(r.WebUrl)
+}
+
+// 在dir下执行git命令，返回去掉首尾空白的标准输出
+func runGit(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("执行git %s失败: %s", args[0], msg)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// 转义路径中的每一段，保留分隔符
+func escapeGitPath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+func isCommitHash(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+package confluence
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-http/confluence/storage"
+)
+
+// 附件备注中内容哈希的前缀，用于判断附件内容是否变化
+const AttachmentHashPrefix = "sha256:"
+
+// 页面中的图片，引用页面附件或外部地址
+type Image struct {
+	Attachment string //附件名称
+	URL        string //外部图片地址，Attachment为空时使用
+	Width      int    //显示宽度（像素），为0时使用原始宽度
+	Height     int    //显示高度（像素），为0时按宽度等比缩放
+	Alt        string //替代文本
+	Title      string //鼠标悬停时显示的标题
+	Caption    string //图片说明，新版编辑器中显示在图片下方
+	Align      string //对齐方式：left、center、right
+	Border     bool   //是否显示边框
+}
+
+func (img Image) Storage() string {
+	var sb strings.Builder
+	sb.WriteString("<ac:image")
+	if img.Align != "" {
+		sb.WriteString(` ac:align="` + storage.EscapeAttr(img.Align) + `"`)
+	}
+	if img.Border {
+		sb.WriteString(` ac:border="true"`)
+	}
+	if img.Width > 0 {
+		sb.WriteString(` ac:width="` + strconv.Itoa(img.Width) + `"`)
+	}
+	if img.Height > 0 {
+		sb.WriteString(` ac:height="` + strconv.Itoa(img.Height) + `"`)
+	}
+	if img.Alt != "" {
+		sb.WriteString(` ac:alt="` + storage.EscapeAttr(img.Alt) + `"`)
+	}
+	if img.Title != "" {
+		sb.WriteString(` ac:title="` + storage.EscapeAttr(img.Title) + `"`)
+	}
+	sb.WriteString(">")
+
+	if img.Caption != "" {
+		sb.WriteString("<ac:caption><p>" + storage.EscapeText(img.Caption) + "</p></ac:caption>")
+	}
+
+	if img.Attachment != "" {
+		sb.WriteString(`<ri:attachment ri:filename="` + storage.EscapeAttr(img.Attachment) + `"/>`)
+	} else {
+		sb.WriteString(`<ri:url ri:value="` + storage.EscapeAttr(img.URL) + `"/>`)
+	}
+
+	sb.WriteString("</ac:image>")
+	return sb.String()
+}
+
+// 计算文件内容的哈希，格式为AttachmentHashPrefix加十六进制的SHA-256
+func FileHash(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", fmt.Errorf("读取文件%s错误: %s", file, err)
+	}
+
+	return AttachmentHashPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// 上传附件，同名附件的备注中保存的哈希与文件一致时不重复上传
+//
+// 附件的备注会被设置为文件的哈希，返回的bool表示是否上传了文件
+func (cli *Client) AttachmentUploadIfChanged(contentId, file, name string) (Attachment, bool, error) {
+	if name == "" {
+		name = filepath.Base(file)
+	}
+
+	hash, err := FileHash(file)
+	if err != nil {
+		return Attachment{}, false, err
+	}
+
+	attachments, err := cli.AttachmentsByContentId(contentId)
+	if err != nil {
+		return Attachment{}, false, fmt.Errorf("获取页面原有附件清单失败: %s", err)
+	}
+
+	attachmentId := ""
+	for _, att := range attachments {
+		if att.Title == name {
+			if att.Comment() == hash {
+				return att, false, nil
+			}
+			attachmentId = att.Id
+			break
+		}
+	}
+
+	att, err := cli.attachmentUpload(contentId, attachmentId, file, name, hash)
+	if err != nil {
+		return Attachment{}, false, fmt.Errorf("上传附件%s错误: %s", file, err)
+	}
+
+	return att, true, nil
+}
+
+// 发布图片的选项
+type ImagePublishOption struct {
+	File  string //本地图片文件，如生成的PNG、SVG图表
+	Image        //图片的显示属性，Attachment为空时使用文件名
+
+	//图片放置的区段，区段的内容会被替换为图片。
+	//为空时替换页面中引用同一附件的图片，页面中没有该图片时添加到页面末尾
+	Section Section
+}
+
+// 上传图片附件并嵌入到页面中，图片和页面内容都未变化时不做修改
+func (cli *Client) ContentImagePublish(contentId string, opt ImagePublishOption) (Content, error) {
+	img := opt.Image
+	if img.Attachment == "" {
+		img.Attachment = filepath.Base(opt.File)
+	}
+
+	_, _, err := cli.AttachmentUploadIfChanged(contentId, opt.File, img.Attachment)
+	if err != nil {
+		return Content{}, err
+	}
+
+	return cli.ContentUpdateFunc(contentId, func(content *Content) error {
+		body, err := placeImage(content.Body.Storage.Value, img, opt.Section)
+		if err != nil {
+			return err
+		}
+
+		content.Body.Storage.Value = body
+		content.Version.Message = "更新图片" + img.Attachment
+		return nil
+	})
+}
+
+// 将图片放入页面内容中，已有的图片与img规范化后相同时返回原内容
+func placeImage(body string, img Image, section Section) (string, error) {
+	if section != nil {
+		data := "<p>" + img.Storage() + "</p>"
+		old, err := SectionContent(body, section)
+		if err != nil {
+			return "", err
+		}
+		if changed, _ := (LocalChangeDetector{}).Changed(old, data); !changed {
+			return body, nil
+		}
+		return ReplaceSection(body, section, data)
+	}
+
+	doc, err := storage.Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("解析页面内容失败: %s", err)
+	}
+
+	nodes, err := storage.ParseFragment(img.Storage())
+	if err != nil {
+		return "", err
+	}
+
+	for _, existing := range doc.Images() {
+		resource := existing.Child("ri:attachment")
+		if resource != nil && resource.Attr("ri:filename") == img.Attachment && resource.Child("ri:page") == nil {
+			if changed, _ := (LocalChangeDetector{}).Changed(existing.String(), img.Storage()); !changed {
+				return body, nil
+			}
+			existing.ReplaceWith(nodes...)
+			return doc.String(), nil
+		}
+	}
+
+	return body + "<p>" + img.Storage() + "</p>", nil
+}
@@ -0,0 +1,134 @@
+package confluence
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlaceImage(t *testing.T) {
+	img := Image{Attachment: "chart.png", Width: 400, Alt: "图表"}
+
+	tests := []struct {
+		name    string
+		body    string
+		section Section
+		want    string
+	}{
+		{
+			name: "没有图片时追加到末尾",
+			body: `<p>text</p>`,
+			want: `<p>text</p><p>` + img.Storage() + `</p>`,
+		},
+		{
+			name: "替换引用同一附件的图片",
+			body: `<p><ac:image ac:width="200"><ri:attachment ri:filename="chart.png"/></ac:image></p><p>after</p>`,
+			want: `<p>` + img.Storage() + `</p><p>after</p>`,
+		},
+		{
+			name: "不替换其他页面的同名附件",
+			body: `<p><ac:image><ri:attachment ri:filename="chart.png"><ri:page ri:content-title="Other"/></ri:attachment></ac:image></p>`,
+			want: `<p><ac:image><ri:attachment ri:filename="chart.png"><ri:page ri:content-title="Other"/></ri:attachment></ac:image></p><p>` + img.Storage() + `</p>`,
+		},
+		{
+			name: "写法不同但内容相同的图片保持不变",
+			body: "<p><ac:image ac:alt='图表'  ac:width=\"400\"><ri:attachment ri:filename=\"chart.png\" /></ac:image></p>",
+			want: "<p><ac:image ac:alt='图表'  ac:width=\"400\"><ri:attachment ri:filename=\"chart.png\" /></ac:image></p>",
+		},
+		{
+			name:    "替换区段",
+			body:    `<h2>Chart</h2><p>old</p><h2>Next</h2>`,
+			section: HeadingSection{Title: "Chart"},
+			want:    `<h2>Chart</h2><p>` + img.Storage() + `</p><h2>Next</h2>`,
+		},
+		{
+			name:    "区段内容相同时保持不变",
+			body:    `<h2>Chart</h2> <p><ac:image ac:width="400" ac:alt="图表"><ri:attachment ri:filename="chart.png"></ri:attachment></ac:image></p><h2>Next</h2>`,
+			section: HeadingSection{Title: "Chart"},
+			want:    `<h2>Chart</h2> <p><ac:image ac:width="400" ac:alt="图表"><ri:attachment ri:filename="chart.png"></ri:attachment></ac:image></p><h2>Next</h2>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := placeImage(tt.body, img, tt.section)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("放置结果为\n%s\n期望\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachmentUploadIfChanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "attachment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "chart.png")
+	err = ioutil.WriteFile(file, []byte("png data"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := FileHash(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		comment  string //服务器上同名附件的备注，为空表示没有同名附件
+		uploaded bool
+		path     string
+	}{
+		{"新附件", "", true, "/rest/api/content/1/child/attachment"},
+		{"内容未变化", hash, false, ""},
+		{"内容变化", AttachmentHashPrefix + "old", true, "/rest/api/content/1/child/attachment/att1/data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uploadPath, uploadComment string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost {
+					uploadPath = r.URL.Path
+					uploadComment = r.FormValue("comment")
+					w.Write([]byte(`{"id":"att1","title":"chart.png"}`))
+					return
+				}
+
+				if tt.comment == "" {
+					w.Write([]byte(`{"results":[]}`))
+					return
+				}
+				w.Write([]byte(`{"results":[{"id":"att1","title":"chart.png","extensions":{"comment":"` + tt.comment + `"}}]}`))
+			}))
+			defer ts.Close()
+
+			cli := New(ts.URL, "", "")
+			cli.ApiVersion = 1
+
+			att, uploaded, err := cli.AttachmentUploadIfChanged("1", file, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if uploaded != tt.uploaded || att.Id != "att1" {
+				t.Errorf("上传结果为%v，附件为%#v", uploaded, att)
+			}
+			if uploadPath != tt.path {
+				t.Errorf("上传地址为%q，期望%q", uploadPath, tt.path)
+			}
+			if tt.uploaded && uploadComment != hash {
+				t.Errorf("附件备注为%q，期望%q", uploadComment, hash)
+			}
+		})
+	}
+}
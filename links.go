@@ -0,0 +1,541 @@
+package confluence
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-http/confluence/storage"
+)
+
+var (
+	linkPageIdRegexp    = regexp.MustCompile(`[?&]pageId=(\d+)`)
+	linkCloudPageRegexp = regexp.MustCompile(`/spaces/[^/]+/pages/(\d+)`)
+	linkDisplayRegexp   = regexp.MustCompile(`/display/([^/?#]+)/([^?#]+)`)
+	linkDownloadRegexp  = regexp.MustCompile(`/download/(?:attachments|thumbnails)/(\d+)/([^?#]+)`)
+)
+
+// 链接的目标类型
+const (
+	LinkToPage       = "page"       //页面
+	LinkToAttachment = "attachment" //页面附件
+	LinkToURL        = "url"        //外部地址
+)
+
+// 链接指向的目标
+type LinkTarget struct {
+	Kind     string //LinkToPage、LinkToAttachment或LinkToURL
+	SpaceKey string //页面所在的空间
+	Title    string //页面标题，附件链接中为附件所在页面的标题
+	PageId   string //站内地址中的页面ID
+	Filename string //附件名称
+	URL      string //a元素的地址
+}
+
+func (t LinkTarget) String() string {
+	var s string
+	switch {
+	case t.PageId != "":
+		s = "pageId=" + t.PageId
+	case t.Title != "":
+		s = t.SpaceKey + ":" + t.Title
+	}
+
+	switch t.Kind {
+	case LinkToAttachment:
+		return s + "^" + t.Filename
+	case LinkToURL:
+		return t.URL
+	}
+	return s
+}
+
+// 无法解析的链接
+type BrokenLink struct {
+	PageId    string //链接所在页面
+	PageTitle string
+	Line      int //链接在页面Storage内容中的位置
+	Column    int
+	Target    LinkTarget
+	Reason    string
+}
+
+func (l BrokenLink) String() string {
+	return fmt.Sprintf("%s(%s):%d:%d: %s %s", l.PageTitle, l.PageId, l.Line, l.Column, l.Target, l.Reason)
+}
+
+// 空间的链接检查器，检查页面中的页面链接、附件链接和站内地址，可选检查外部地址
+//
+// 检查过程中会缓存页面和附件清单，一个检查器只应检查一次
+type LinkChecker struct {
+	Client   *Client
+	Space    string
+	External bool //是否检查外部地址，通过HEAD请求判断
+	Timeout  time.Duration
+
+	titles      map[string]map[string]string //空间、标题到页面ID的映射
+	loaded      map[string]bool              //已获取全部页面清单的空间
+	pageIds     map[string]bool              //页面ID是否存在
+	attachments map[string]map[string]bool   //页面ID到附件名称的映射
+	urls        map[string]string            //外部地址的检查结果，为空表示正常
+}
+
+// 创建空间的链接检查器
+func NewLinkChecker(cli *Client, space string) *LinkChecker {
+	return &LinkChecker{
+		Client:      cli,
+		Space:       space,
+		Timeout:     10 * time.Second,
+		titles:      make(map[string]map[string]string),
+		loaded:      make(map[string]bool),
+		pageIds:     make(map[string]bool),
+		attachments: make(map[string]map[string]bool),
+		urls:        make(map[string]string),
+	}
+}
+
+// 检查空间所有页面和博客中的链接
+func (c *LinkChecker) Check() ([]BrokenLink, error) {
+	pages, err := c.Client.AllSpaceContentsWithOpt(c.Space, ContentTypePage, ExpandOpt(Expand.Body.Storage))
+	if err != nil {
+		return nil, fmt.Errorf("获取空间页面失败: %s", err)
+	}
+
+	blogs, err := c.Client.AllSpaceContentsWithOpt(c.Space, "blogpost", ExpandOpt(Expand.Body.Storage))
+	if err != nil {
+		return nil, fmt.Errorf("获取空间博客失败: %s", err)
+	}
+
+	//页面链接只能按标题指向页面，博客只记录ID
+	titles := make(map[string]string)
+	for _, page := range pages {
+		titles[page.Title] = page.Id
+		c.pageIds[page.Id] = true
+	}
+	for _, blog := range blogs {
+		c.pageIds[blog.Id] = true
+	}
+	c.titles[c.Space] = titles
+	c.loaded[c.Space] = true
+
+	var broken []BrokenLink
+	for _, page := range append(pages, blogs...) {
+		links, err := c.CheckContent(page)
+		if err != nil {
+			return broken, err
+		}
+		broken = append(broken, links...)
+	}
+
+	return broken, nil
+}
+
+// 检查单个页面中的链接，页面需要包含Storage内容
+func (c *LinkChecker) CheckContent(content Content) ([]BrokenLink, error) {
+	doc, err := storage.Parse(content.Body.Storage.Value)
+	if err != nil {
+		return []BrokenLink{{PageId: content.Id, PageTitle: content.Title, Reason: "页面内容格式错误: " + err.Error()}}, nil
+	}
+
+	var broken []BrokenLink
+	report := func(node *storage.Node, target LinkTarget, reason string) {
+		broken = append(broken, BrokenLink{
+			PageId: content.Id, PageTitle: content.Title,
+			Line: node.Line, Column: node.Column,
+			Target: target, Reason: reason,
+		})
+	}
+
+	space := content.Space.Key
+	if space == "" {
+		space = c.Space
+	}
+
+	for _, node := range doc.Find(func(n *storage.Node) bool {
+		return n.Is("ri:page") || n.Is("ri:attachment") || n.Is("a")
+	}) {
+		var target LinkTarget
+		switch {
+		case node.Is("ri:page"):
+			//附件所在的页面在检查附件时一并检查
+			if node.Parent != nil && node.Parent.Is("ri:attachment") {
+				continue
+			}
+			target = LinkTarget{Kind: LinkToPage, SpaceKey: node.Attr("ri:space-key"), Title: node.Attr("ri:content-title")}
+			if target.SpaceKey == "" {
+				target.SpaceKey = space
+			}
+			//未指定标题的链接指向当前页面
+			if target.Title == "" {
+				continue
+			}
+
+		case node.Is("ri:attachment"):
+			target = LinkTarget{Kind: LinkToAttachment, SpaceKey: space, PageId: content.Id, Filename: node.Attr("ri:filename")}
+			if page := node.Child("ri:page"); page != nil {
+				target.PageId = ""
+				target.Title = page.Attr("ri:content-title")
+				if key := page.Attr("ri:space-key"); key != "" {
+					target.SpaceKey = key
+				}
+			}
+
+		default:
+			href := node.Attr("href")
+			if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "mailto:") {
+				continue
+			}
+			target = c.hrefTarget(href)
+		}
+
+		reason, err := c.resolve(target)
+		if err != nil {
+			return broken, err
+		}
+		if reason != "" {
+			report(node, target, reason)
+		}
+	}
+
+	return broken, nil
+}
+
+// 检查空间所有页面和博客中的链接
+func (cli *Client) CheckSpaceLinks(space string) ([]BrokenLink, error) {
+	return NewLinkChecker(cli, space).Check()
+}
+
+// 解析a元素的地址，站内地址解析为页面或附件
+func (c *LinkChecker) hrefTarget(href string) LinkTarget {
+	target := LinkTarget{Kind: LinkToURL, URL: href}
+
+	local, ok := c.localPath(href)
+	if !ok {
+		return target
+	}
+
+	if m := linkDownloadRegexp.FindStringSubmatch(local); m != nil {
+		filename, err := url.PathUnescape(m[2])
+		if err == nil {
+			return LinkTarget{Kind: LinkToAttachment, PageId: m[1], Filename: filename, URL: href}
+		}
+	}
+
+	if m := linkPageIdRegexp.FindStringSubmatch(local); m != nil {
+		return LinkTarget{Kind: LinkToPage, PageId: m[1], URL: href}
+	}
+	if m := linkCloudPageRegexp.FindStringSubmatch(local); m != nil {
+		return LinkTarget{Kind: LinkToPage, PageId: m[1], URL: href}
+	}
+	if m := linkDisplayRegexp.FindStringSubmatch(local); m != nil {
+		title, err := url.QueryUnescape(m[2])
+		if err == nil {
+			return LinkTarget{Kind: LinkToPage, SpaceKey: m[1], Title: title, URL: href}
+		}
+	}
+
+	return target
+}
+
+// 站内地址的路径（包括查询参数），不是站内地址时返回false
+func (c *LinkChecker) localPath(href string) (string, bool) {
+	return sitePath(c.Client.Hostname, href)
+}
+
+// 地址在站点site中的路径（包括查询参数，不包括锚点），不是站内地址时返回false
+//
+// 站内地址为相对路径，或者以站点的协议和主机名开头的地址
+func sitePath(site, href string) (string, bool) {
+	start := sitePathStart(site, href)
+	if start < 0 {
+		return "", false
+	}
+
+	href = href[start:]
+	if k := strings.IndexByte(href, '#'); k >= 0 {
+		href = href[:k]
+	}
+	return href, true
+}
+
+// 站内地址中路径的起始位置，不是站内地址时返回-1
+func sitePathStart(site, href string) int {
+	u, err := url.Parse(site)
+	if err != nil {
+		return -1
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	switch {
+	case u.Host != "" && strings.HasPrefix(href, origin+"/"):
+		return len(origin)
+	case strings.HasPrefix(href, "/") && !strings.HasPrefix(href, "//"):
+		return 0
+	default:
+		return -1
+	}
+}
+
+// 解析链接目标，返回无法解析的原因，为空表示目标存在
+func (c *LinkChecker) resolve(target LinkTarget) (string, error) {
+	switch target.Kind {
+	case LinkToURL:
+		if !c.External || !strings.HasPrefix(target.URL, "http") {
+			return "", nil
+		}
+		return c.checkURL(target.URL), nil
+	}
+
+	pageId := target.PageId
+	if pageId != "" {
+		exists, err := c.pageExists(pageId)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return "页面不存在", nil
+		}
+	} else {
+		id, err := c.pageId(target.SpaceKey, target.Title)
+		if err != nil {
+			return "", err
+		}
+		if id == "" {
+			return "页面不存在", nil
+		}
+		pageId = id
+	}
+
+	if target.Kind != LinkToAttachment {
+		return "", nil
+	}
+
+	names, err := c.pageAttachments(pageId)
+	if err != nil {
+		return "", err
+	}
+	if !names[target.Filename] {
+		return "附件不存在", nil
+	}
+
+	return "", nil
+}
+
+// 指定空间、标题的页面ID，页面不存在时返回空
+func (c *LinkChecker) pageId(space, title string) (string, error) {
+	titles, ok := c.titles[space]
+	if !ok {
+		titles = make(map[string]string)
+		c.titles[space] = titles
+	}
+
+	//已获取整个空间的页面清单时不再单独查询
+	if id, found := titles[title]; found || c.loaded[space] {
+		return id, nil
+	}
+
+	content, err := c.Client.ContentBySpaceAndTitle(space, title)
+	if err != nil {
+		return "", fmt.Errorf("查找页面%s:%s失败: %s", space, title, err)
+	}
+
+	titles[title] = content.Id
+	if content.Id != "" {
+		c.pageIds[content.Id] = true
+	}
+	return content.Id, nil
+}
+
+// 指定ID的页面是否存在，只有服务器返回404时视为不存在
+func (c *LinkChecker) pageExists(id string) (bool, error) {
+	if exists, found := c.pageIds[id]; found {
+		return exists, nil
+	}
+
+	_, err := c.Client.ContentById(id)
+	if err != nil && !IsNotFound(err) {
+		return false, fmt.Errorf("查找页面%s失败: %s", id, err)
+	}
+
+	c.pageIds[id] = err == nil
+	return err == nil, nil
+}
+
+// 页面的附件名称
+func (c *LinkChecker) pageAttachments(pageId string) (map[string]bool, error) {
+	if names, found := c.attachments[pageId]; found {
+		return names, nil
+	}
+
+	attachments, err := c.Client.AttachmentsByContentId(pageId)
+	if err != nil {
+		return nil, fmt.Errorf("获取页面%s的附件失败: %s", pageId, err)
+	}
+
+	names := make(map[string]bool)
+	for _, att := range attachments {
+		names[att.Title] = true
+	}
+	c.attachments[pageId] = names
+	return names, nil
+}
+
+// 检查外部地址，不支持HEAD请求时使用GET请求
+func (c *LinkChecker) checkURL(link string) string {
+	if reason, found := c.urls[link]; found {
+		return reason
+	}
+
+	client := &http.Client{Timeout: c.Timeout}
+
+	reason := ""
+	resp, err := client.Head(link)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = client.Get(link)
+	}
+	if err != nil {
+		reason = err.Error()
+	} else {
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			reason = resp.Status
+		}
+	}
+
+	c.urls[link] = reason
+	return reason
+}
+
+// 页面标题的变更
+type TitleChange struct {
+	SpaceKey string
+	OldTitle string
+	NewTitle string
+}
+
+// 将内容中对旧标题页面的引用改为新标题，space为内容所在的空间，site为Confluence的地址
+//
+// 修改ri:page引用（包括链接、附件和宏参数中的引用）和/display/空间/标题形式的站内地址，
+// 其他站点的地址不修改，site为空时只修改相对路径的地址。返回修改后的内容和修改的引用数量
+func RewritePageLinks(body, space, site string, changes []TitleChange) (string, int, error) {
+	doc, err := storage.Parse(body)
+	if err != nil {
+		return "", 0, fmt.Errorf("解析页面内容失败: %s", err)
+	}
+
+	newTitle := func(key, title string) (string, bool) {
+		for _, change := range changes {
+			if change.SpaceKey == key && change.OldTitle == title {
+				return change.NewTitle, true
+			}
+		}
+		return "", false
+	}
+
+	count := 0
+	for _, node := range doc.Elements("ri:page") {
+		key := node.Attr("ri:space-key")
+		if key == "" {
+			key = space
+		}
+
+		if title, ok := newTitle(key, node.Attr("ri:content-title")); ok {
+			node.SetAttr("ri:content-title", title)
+			count++
+		}
+	}
+
+	for _, node := range doc.Elements("a") {
+		href := node.Attr("href")
+		start := sitePathStart(site, href)
+		if start < 0 {
+			continue
+		}
+
+		local, _ := sitePath(site, href)
+		loc := linkDisplayRegexp.FindStringSubmatchIndex(local)
+		if loc == nil {
+			continue
+		}
+
+		old, err := url.QueryUnescape(local[loc[4]:loc[5]])
+		if err != nil {
+			continue
+		}
+
+		if title, ok := newTitle(local[loc[2]:loc[3]], old); ok {
+			node.SetAttr("href", href[:start+loc[4]]+url.QueryEscape(title)+href[start+loc[5]:])
+			count++
+		}
+	}
+
+	if count == 0 {
+		return body, 0, nil
+	}
+	return doc.String(), count, nil
+}
+
+// 页面链接的修改结果
+type LinkRewriteResult struct {
+	PageId    string
+	PageTitle string
+	SpaceKey  string
+	Changes   int    //修改的引用数量
+	Error     string //页面内容无法解析或更新失败时的错误信息，此时Changes为0
+}
+
+// 将空间所有页面和博客中对旧标题页面的引用改为新标题，dryRun为true时只统计不修改
+//
+// 单个页面处理失败时记录在结果的Error中并继续处理其他页面
+func (cli *Client) RewriteSpaceLinks(space string, changes []TitleChange, dryRun bool) ([]LinkRewriteResult, error) {
+	pages, err := cli.allSpacePagesAndBlogs(space, ExpandOpt(Expand.Body.Storage))
+	if err != nil {
+		return nil, fmt.Errorf("获取空间页面失败: %s", err)
+	}
+
+	var results []LinkRewriteResult
+	for _, page := range pages {
+		result, err := cli.rewriteContentLinks(page, space, changes, dryRun)
+		if err != nil {
+			result.Changes = 0
+			result.Error = err.Error()
+		}
+		if result.Changes > 0 || result.Error != "" {
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// 修改单个页面中的引用，页面需要包含Storage内容。实际修改时以最新版本的内容为准
+func (cli *Client) rewriteContentLinks(page Content, space string, changes []TitleChange, dryRun bool) (LinkRewriteResult, error) {
+	result := LinkRewriteResult{PageId: page.Id, PageTitle: page.Title, SpaceKey: space}
+
+	_, n, err := RewritePageLinks(page.Body.Storage.Value, space, cli.Hostname, changes)
+	if err != nil || n == 0 || dryRun {
+		result.Changes = n
+		return result, err
+	}
+
+	_, err = cli.ContentUpdateFunc(page.Id, func(content *Content) error {
+		body, n, err := RewritePageLinks(content.Body.Storage.Value, space, cli.Hostname, changes)
+		if err != nil {
+			return err
+		}
+
+		result.Changes = n
+		content.Body.Storage.Value = body
+		content.Version.Message = "更新页面链接"
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("更新页面%s的链接失败: %s", page.Title, err)
+	}
+
+	return result, nil
+}
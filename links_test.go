@@ -0,0 +1,94 @@
+package confluence
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRewritePageLinks(t *testing.T) {
+	changes := []TitleChange{{SpaceKey: "DOC", OldTitle: "Old Page", NewTitle: "New & Page"}}
+	site := "https://wiki.example.com/confluence"
+
+	tests := []struct {
+		name  string
+		body  string
+		want  string
+		count int
+	}{
+		{
+			name:  "页面引用",
+			body:  `<ac:link><ri:page ri:content-title="Old Page"/></ac:link><ac:link><ri:page ri:space-key="OTHER" ri:content-title="Old Page"/></ac:link>`,
+			want:  `<ac:link><ri:page ri:content-title="New &amp; Page"/></ac:link><ac:link><ri:page ri:space-key="OTHER" ri:content-title="Old Page"/></ac:link>`,
+			count: 1,
+		},
+		{
+			name:  "相对路径",
+			body:  `<a href="/confluence/display/DOC/Old+Page#top">x</a>`,
+			want:  `<a href="/confluence/display/DOC/New+%26+Page#top">x</a>`,
+			count: 1,
+		},
+		{
+			name:  "本站地址",
+			body:  `<a href="https://wiki.example.com/display/DOC/Old+Page?focusedCommentId=1">x</a>`,
+			want:  `<a href="https://wiki.example.com/display/DOC/New+%26+Page?focusedCommentId=1">x</a>`,
+			count: 1,
+		},
+		{
+			name: "其他站点的地址",
+			body: `<a href="https://other.example.com/display/DOC/Old+Page">x</a><a href="//wiki.example.com/display/DOC/Old+Page">y</a>`,
+			want: `<a href="https://other.example.com/display/DOC/Old+Page">x</a><a href="//wiki.example.com/display/DOC/Old+Page">y</a>`,
+		},
+		{
+			name: "锚点中的路径",
+			body: `<a href="/pages/viewpage.action?pageId=1#/display/DOC/Old+Page">x</a>`,
+			want: `<a href="/pages/viewpage.action?pageId=1#/display/DOC/Old+Page">x</a>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, count, err := RewritePageLinks(tt.body, "DOC", site, changes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || count != tt.count {
+				t.Errorf("修改结果为%d处\n%s\n期望%d处\n%s", count, got, tt.count, tt.want)
+			}
+		})
+	}
+}
+
+// 扫描空间时同时修改页面和博客中的引用
+func TestRewriteSpaceLinksIncludesBlogs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/space/DOC/content/page":
+			w.Write([]byte(`{"results":[{"id":"1","type":"page","title":"Page",` +
+				`"body":{"storage":{"value":"<ac:link><ri:page ri:content-title=\"Old\"/></ac:link>"}}}]}`))
+		case "/rest/api/space/DOC/content/blogpost":
+			w.Write([]byte(`{"results":[{"id":"2","type":"blogpost","title":"Blog",` +
+				`"body":{"storage":{"value":"<a href=\"/display/DOC/Old\">x</a>"}}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	cli := New(ts.URL, "", "")
+	cli.ApiVersion = 1
+
+	results, err := cli.RewriteSpaceLinks("DOC", []TitleChange{{SpaceKey: "DOC", OldTitle: "Old", NewTitle: "New"}}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results[0].PageId != "1" || results[1].PageId != "2" {
+		t.Fatalf("修改结果为%+v", results)
+	}
+	for _, result := range results {
+		if result.Changes != 1 || result.Error != "" {
+			t.Errorf("%s的修改结果为%+v", result.PageTitle, result)
+		}
+	}
+}
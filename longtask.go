@@ -0,0 +1,56 @@
+package confluence
+
+import (
+	"strings"
+)
+
+// Confluence中的长任务（删除空间、复制页面树、导出空间等操作会返回长任务）
+type LongTask struct {
+	Id                 string            `json:"id,omitempty"`
+	Name               LongTaskName      `json:"name,omitempty"`
+	ElapsedTime        int64             `json:"elapsedTime,omitempty"`
+	PercentageComplete int               `json:"percentageComplete,omitempty"`
+	Successful         bool              `json:"successful,omitempty"`
+	Finished           bool              `json:"finished,omitempty"`
+	Messages           []LongTaskMessage `json:"messages,omitempty"`
+	Status             string            `json:"status,omitempty"`
+	Links              *LinkResp         `json:"_links,omitempty"`
+}
+
+// 长任务的名称
+type LongTaskName struct {
+	Key  string        `json:"key,omitempty"`
+	Args []interface{} `json:"args,omitempty"`
+}
+
+// 长任务的进度消息
+type LongTaskMessage struct {
+	Translation string        `json:"translation,omitempty"`
+	Args        []interface{} `json:"args,omitempty"`
+}
+
+// 提交长任务后返回的任务引用
+type LongTaskSubmission struct {
+	Id    string `json:"id,omitempty"`
+	Links struct {
+		Status string `json:"status,omitempty"`
+	} `json:"links,omitempty"`
+}
+
+// 长任务是否已结束
+//
+// Cloud版本会返回finished字段，Server版本只能通过完成百分比判断
+func (task *LongTask) IsFinished() bool {
+	return task.Finished || task.PercentageComplete >= 100
+}
+
+// 长任务的消息汇总
+func (task *LongTask) Message() string {
+	var msgs []string
+	for _, m := range task.Messages {
+		if m.Translation != "" {
+			msgs = append(msgs, m.Translation)
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
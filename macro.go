@@ -0,0 +1,377 @@
+package confluence
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-http/confluence/storage"
+)
+
+// 可以输出为Storage格式的内容
+type StorageMarkup interface {
+	Storage() string
+}
+
+// 拼接多个Storage格式的内容
+func JoinStorage(parts ...StorageMarkup) string {
+	var sb strings.Builder
+	for _, part := range parts {
+		sb.WriteString(part.Storage())
+	}
+	return sb.String()
+}
+
+// 宏的参数
+type MacroParam struct {
+	Name    string
+	Value   string //参数值，输出时转义
+	Storage string //Storage格式的参数值（如页面链接），不为空时忽略Value
+}
+
+// 宏内容体的类型
+type MacroBodyType int
+
+const (
+	MacroNoBody        MacroBodyType = iota //没有内容体
+	MacroRichTextBody                       //富文本内容体ac:rich-text-body，内容为Storage格式
+	MacroPlainTextBody                      //纯文本内容体ac:plain-text-body，内容以CDATA保存
+)
+
+// 通用的宏，其他宏构造器最终转换为StructuredMacro输出
+type StructuredMacro struct {
+	Name     string
+	Id       string //ac:macro-id，为空时不输出
+	Params   []MacroParam
+	BodyType MacroBodyType
+	Body     string
+}
+
+// 添加参数，值为空时忽略
+func (m *StructuredMacro) Param(name, value string) *StructuredMacro {
+	if value != "" {
+		m.Params = append(m.Params, MacroParam{Name: name, Value: value})
+	}
+	return m
+}
+
+// 添加布尔类型的参数，值为false时忽略
+func (m *StructuredMacro) BoolParam(name string, value bool) *StructuredMacro {
+	if value {
+		m.Params = append(m.Params, MacroParam{Name: name, Value: "true"})
+	}
+	return m
+}
+
+// 添加整数类型的参数，值为0时忽略
+func (m *StructuredMacro) IntParam(name string, value int) *StructuredMacro {
+	if value != 0 {
+		m.Params = append(m.Params, MacroParam{Name: name, Value: strconv.Itoa(value)})
+	}
+	return m
+}
+
+// 输出为Storage格式
+func (m StructuredMacro) Storage() string {
+	var sb strings.Builder
+	sb.WriteString(`<ac:structured-macro ac:name="` + storage.EscapeAttr(m.Name) + `" ac:schema-version="1"`)
+	if m.Id != "" {
+		sb.WriteString(` ac:macro-id="` + storage.EscapeAttr(m.Id) + `"`)
+	}
+	sb.WriteString(">")
+
+	for _, param := range m.Params {
+		value := param.Storage
+		if value == "" {
+			value = storage.EscapeText(param.Value)
+		}
+		sb.WriteString(`<ac:parameter ac:name="` + storage.EscapeAttr(param.Name) + `">` + value + "</ac:parameter>")
+	}
+
+	switch m.BodyType {
+	case MacroRichTextBody:
+		sb.WriteString("<ac:rich-text-body>" + m.Body + "</ac:rich-text-body>")
+	case MacroPlainTextBody:
+		sb.WriteString("<ac:plain-text-body>" + storage.NewCDATA(m.Body).String() + "</ac:plain-text-body>")
+	}
+
+	sb.WriteString("</ac:structured-macro>")
+	return sb.String()
+}
+
+// 提示面板的类型
+type PanelType string
+
+const (
+	PanelInfo    PanelType = "info"
+	PanelNote    PanelType = "note"
+	PanelWarning PanelType = "warning"
+	PanelTip     PanelType = "tip"
+)
+
+// 信息、注意、警告、提示面板宏
+type NoteMacro struct {
+	Type     PanelType
+	Title    string
+	HideIcon bool   //不显示图标
+	Body     string //Storage格式的内容
+}
+
+func (m NoteMacro) Storage() string {
+	macro := StructuredMacro{Name: string(m.Type), BodyType: MacroRichTextBody, Body: m.Body}
+	if m.HideIcon {
+		macro.Param("icon", "false")
+	}
+	macro.Param("title", m.Title)
+	return macro.Storage()
+}
+
+// 代码块宏
+type CodeMacro struct {
+	Language    string //语言，如java、go、bash
+	Title       string
+	Theme       string //主题，如Midnight、Eclipse
+	LineNumbers bool   //显示行号
+	FirstLine   int    //起始行号
+	Collapse    bool   //默认折叠
+	Code        string
+}
+
+func (m CodeMacro) Storage() string {
+	macro := StructuredMacro{Name: "code", BodyType: MacroPlainTextBody, Body: m.Code}
+	macro.Param("language", m.Language).Param("title", m.Title).Param("theme", m.Theme)
+	macro.BoolParam("linenumbers", m.LineNumbers).IntParam("firstline", m.FirstLine).BoolParam("collapse", m.Collapse)
+	return macro.Storage()
+}
+
+// 折叠展开宏
+type ExpandMacro struct {
+	Title string //折叠时显示的文本
+	Body  string //Storage格式的内容
+}
+
+func (m ExpandMacro) Storage() string {
+	macro := StructuredMacro{Name: "expand", BodyType: MacroRichTextBody, Body: m.Body}
+	macro.Param("title", m.Title)
+	return macro.Storage()
+}
+
+// 目录宏
+type TOCMacro struct {
+	MinLevel    int
+	MaxLevel    int
+	Type        string //list或flat
+	Style       string //列表样式，如disc、none
+	Outline     bool   //显示章节编号
+	Include     string //只包含匹配的标题（正则表达式）
+	Exclude     string //排除匹配的标题（正则表达式）
+	HideInPrint bool   //打印时不显示
+}
+
+func (m TOCMacro) Storage() string {
+	macro := StructuredMacro{Name: "toc"}
+	macro.IntParam("minLevel", m.MinLevel).IntParam("maxLevel", m.MaxLevel)
+	macro.Param("type", m.Type).Param("style", m.Style).BoolParam("outline", m.Outline)
+	macro.Param("include", m.Include).Param("exclude", m.Exclude)
+	if m.HideInPrint {
+		macro.Param("printable", "false")
+	}
+	return macro.Storage()
+}
+
+// 子页面列表宏
+type ChildrenMacro struct {
+	PageTitle string //父页面标题，为空时为当前页面
+	SpaceKey  string
+	All       bool   //显示所有层级的子页面
+	Depth     int    //显示的层级
+	Sort      string //排序方式：creation、title、modified
+	Reverse   bool
+	Style     string //标题样式，如h3
+	Excerpt   string //显示摘要：none、simple、rich content
+	First     int    //只显示前几个子页面
+}
+
+func (m ChildrenMacro) Storage() string {
+	macro := StructuredMacro{Name: "children"}
+	if m.PageTitle != "" {
+		macro.Params = append(macro.Params, MacroParam{Name: "page", Storage: PageLink{Title: m.PageTitle, SpaceKey: m.SpaceKey}.Storage()})
+	}
+	macro.BoolParam("all", m.All).IntParam("depth", m.Depth).Param("sort", m.Sort).BoolParam("reverse", m.Reverse)
+	macro.Param("style", m.Style).Param("excerptType", m.Excerpt).IntParam("first", m.First)
+	return macro.Storage()
+}
+
+// 状态标签的颜色
+type StatusColour string
+
+const (
+	StatusGrey   StatusColour = "Grey"
+	StatusRed    StatusColour = "Red"
+	StatusYellow StatusColour = "Yellow"
+	StatusGreen  StatusColour = "Green"
+	StatusBlue   StatusColour = "Blue"
+)
+
+// 状态标签宏
+type StatusMacro struct {
+	Title  string
+	Colour StatusColour
+	Subtle bool //使用浅色样式
+}
+
+func (m StatusMacro) Storage() string {
+	macro := StructuredMacro{Name: "status"}
+	macro.Param("colour", string(m.Colour)).Param("title", m.Title).BoolParam("subtle", m.Subtle)
+	return macro.Storage()
+}
+
+// Jira问题宏，指定Key时显示单个问题，否则显示JQL查询结果
+type JiraMacro struct {
+	Key           string
+	JQL           string
+	Server        string //Jira应用链接的名称
+	ServerId      string //Jira应用链接的ID
+	Columns       string //显示的列，以逗号分隔
+	MaximumIssues int
+	Count         bool //只显示问题数量
+}
+
+func (m JiraMacro) Storage() string {
+	macro := StructuredMacro{Name: "jira"}
+	macro.Param("server", m.Server).Param("serverId", m.ServerId)
+	if m.Key != "" {
+		macro.Param("key", m.Key)
+	} else {
+		macro.Param("jqlQuery", m.JQL).Param("columns", m.Columns).IntParam("maximumIssues", m.MaximumIssues).BoolParam("count", m.Count)
+	}
+	return macro.Storage()
+}
+
+// 面板宏
+type PanelMacro struct {
+	Title        string
+	BorderStyle  string //边框样式，如solid、dashed
+	BorderColor  string
+	BgColor      string
+	TitleBgColor string
+	TitleColor   string
+	Body         string //Storage格式的内容
+}
+
+func (m PanelMacro) Storage() string {
+	macro := StructuredMacro{Name: "panel", BodyType: MacroRichTextBody, Body: m.Body}
+	macro.Param("title", m.Title).Param("borderStyle", m.BorderStyle).Param("borderColor", m.BorderColor)
+	macro.Param("bgColor", m.BgColor).Param("titleBGColor", m.TitleBgColor).Param("titleColor", m.TitleColor)
+	return macro.Storage()
+}
+
+// 锚点宏，可以通过页面链接的ac:anchor跳转
+type AnchorMacro struct {
+	Name string
+}
+
+func (m AnchorMacro) Storage() string {
+	macro := StructuredMacro{Name: "anchor"}
+	macro.Params = append(macro.Params, MacroParam{Name: "", Value: m.Name})
+	return macro.Storage()
+}
+
+// 摘要宏，摘要内容可以被其他页面的摘要引用宏和子页面列表引用
+type ExcerptMacro struct {
+	Name   string //多个摘要时的名称
+	Hidden bool   //在当前页面中隐藏
+	Inline bool   //以行内形式显示
+	Body   string //Storage格式的内容
+}
+
+func (m ExcerptMacro) Storage() string {
+	name := "excerpt"
+	if m.Inline {
+		name = "excerpt-inline"
+	}
+
+	macro := StructuredMacro{Name: name, BodyType: MacroRichTextBody, Body: m.Body}
+	macro.Param("name", m.Name).BoolParam("hidden", m.Hidden)
+	return macro.Storage()
+}
+
+// 摘要引用宏，显示其他页面的摘要
+type ExcerptIncludeMacro struct {
+	PageTitle string
+	SpaceKey  string
+	Name      string //摘要的名称
+	NoPanel   bool   //不显示边框
+}
+
+func (m ExcerptIncludeMacro) Storage() string {
+	macro := StructuredMacro{Name: "excerpt-include"}
+	macro.Params = append(macro.Params, MacroParam{Name: "", Storage: PageLink{Title: m.PageTitle, SpaceKey: m.SpaceKey}.Storage()})
+	macro.Param("name", m.Name).BoolParam("nopanel", m.NoPanel)
+	return macro.Storage()
+}
+
+// 页面引用宏，显示其他页面的全部内容
+type IncludeMacro struct {
+	PageTitle string
+	SpaceKey  string
+}
+
+func (m IncludeMacro) Storage() string {
+	macro := StructuredMacro{Name: "include"}
+	macro.Params = append(macro.Params, MacroParam{Name: "", Storage: PageLink{Title: m.PageTitle, SpaceKey: m.SpaceKey}.Storage()})
+	return macro.Storage()
+}
+
+// 页面链接
+type PageLink struct {
+	Title    string
+	SpaceKey string //为空时为当前空间
+	Anchor   string
+	Text     string //链接文本，为空时显示页面标题
+}
+
+func (l PageLink) Storage() string {
+	var sb strings.Builder
+	sb.WriteString("<ac:link")
+	if l.Anchor != "" {
+		sb.WriteString(` ac:anchor="` + storage.EscapeAttr(l.Anchor) + `"`)
+	}
+	sb.WriteString(`><ri:page ri:content-title="` + storage.EscapeAttr(l.Title) + `"`)
+	if l.SpaceKey != "" {
+		sb.WriteString(` ri:space-key="` + storage.EscapeAttr(l.SpaceKey) + `"`)
+	}
+	sb.WriteString("/>")
+	if l.Text != "" {
+		sb.WriteString("<ac:plain-text-link-body>" + storage.NewCDATA(l.Text).String() + "</ac:plain-text-link-body>")
+	}
+	sb.WriteString("</ac:link>")
+	return sb.String()
+}
+
+// 提及用户，Cloud版本使用AccountId，Server版本使用UserKey或Username
+type UserMention struct {
+	AccountId string
+	UserKey   string
+	Username  string
+}
+
+func (u UserMention) Storage() string {
+	var attr string
+	switch {
+	case u.AccountId != "":
+		attr = `ri:account-id="` + storage.EscapeAttr(u.AccountId) + `"`
+	case u.UserKey != "":
+		attr = `ri:userkey="` + storage.EscapeAttr(u.UserKey) + `"`
+	default:
+		attr = `ri:username="` + storage.EscapeAttr(u.Username) + `"`
+	}
+	return "<ac:link><ri:user " + attr + "/></ac:link>"
+}
+
+// 日期
+type Date time.Time
+
+func (d Date) Storage() string {
+	return `<time datetime="` + time.Time(d).Format("2006-01-02") + `"/>`
+}
@@ -0,0 +1,536 @@
+package confluence
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-http/confluence/storage"
+)
+
+// Storage格式转换为Markdown的选项
+type MarkdownOption struct {
+	// 返回页面引用（ac:link中的ri:page）的链接地址，spaceKey为空表示当前空间，
+	// 返回空字符串时只输出链接文本。为空时使用"标题.md"
+	PageLink func(spaceKey, title, anchor string) string
+
+	// 返回附件引用（ac:image、ac:link中的ri:attachment）的链接地址，pageTitle为空表示当前页面。
+	// 为空时使用附件文件名，其他页面的附件使用"页面标题/文件名"
+	AttachmentLink func(pageTitle, filename string) string
+}
+
+// 面板宏对应的GitHub提示块类型
+var markdownAdmonitions = map[string]string{
+	"info":    "NOTE",
+	"tip":     "TIP",
+	"note":    "IMPORTANT",
+	"warning": "WARNING",
+}
+
+// 作为行内元素处理的宏
+var markdownInlineMacros = map[string]bool{
+	"status": true,
+	"jira":   true,
+	"anchor": true,
+}
+
+// 块级元素
+var markdownBlockElements = map[string]bool{
+	"p": true, "div": true, "hr": true, "pre": true, "blockquote": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "ac:task-list": true,
+	"ac:layout": true, "ac:layout-section": true, "ac:layout-cell": true,
+}
+
+var (
+	markdownSpaceRegexp      = regexp.MustCompile(`\s+`)
+	markdownBlankRegexp      = regexp.MustCompile(`\n{3,}`)
+	markdownNestedListRegexp = regexp.MustCompile(`\n\n((?:- |\d+\. ))`)
+	markdownEscapeReplacer   = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+	markdownTableEscaper     = strings.NewReplacer("|", `\|`, "\n", "<br>")
+	markdownLinkTextCleaner  = strings.NewReplacer("\n", " ")
+)
+
+// 将Storage格式的内容转换为Markdown
+//
+// 支持标题、列表、表格、代码宏、信息/提示/注意/警告面板、页面和附件链接、图片等常见元素，
+// 无法转换的宏只保留其内容
+func StorageToMarkdown(body string, opt *MarkdownOption) (string, error) {
+	doc, err := parseNormalizedStorage(body)
+	if err != nil {
+		return "", err
+	}
+
+	if opt == nil {
+		opt = &MarkdownOption{}
+	}
+
+	conv := &markdownConverter{opt: opt}
+	md := strings.TrimSpace(conv.blocks(textMergedChildren(doc)))
+	if md == "" {
+		return "", nil
+	}
+
+	return md + "\n", nil
+}
+
+// Storage格式到Markdown的转换器
+type markdownConverter struct {
+	opt *MarkdownOption
+}
+
+// 节点是否为块级元素
+func (conv *markdownConverter) isBlock(node *storage.Node) bool {
+	if node.Name == "ac:structured-macro" {
+		return !markdownInlineMacros[node.Attr("ac:name")]
+	}
+	return markdownBlockElements[node.Name]
+}
+
+// 转换一组节点为Markdown块，连续的行内节点合并为一个段落
+func (conv *markdownConverter) blocks(nodes []*storage.Node) string {
+	var blocks []string
+	var inline strings.Builder
+
+	flush := func() {
+		text := strings.TrimSpace(inline.String())
+		if text != "" {
+			blocks = append(blocks, text)
+		}
+		inline.Reset()
+	}
+
+	for _, node := range nodes {
+		if node.Type == storage.TextNode || !conv.isBlock(node) {
+			inline.WriteString(conv.inline(node))
+			continue
+		}
+
+		flush()
+		if block := strings.TrimRight(conv.block(node), "\n "); strings.TrimSpace(block) != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	flush()
+
+	return markdownBlankRegexp.ReplaceAllString(strings.Join(blocks, "\n\n"), "\n\n")
+}
+
+// 转换块级元素
+func (conv *markdownConverter) block(node *storage.Node) string {
+	switch node.Name {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(node.Name[1] - '0')
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(conv.inlines(textMergedChildren(node)))
+	case "p":
+		return strings.TrimSpace(conv.inlines(textMergedChildren(node)))
+	case "hr":
+		return "---"
+	case "pre":
+		return markdownFence("", node.Text())
+	case "blockquote":
+		return prefixLines(conv.blocks(textMergedChildren(node)), "> ")
+	case "ul", "ol":
+		return conv.list(node)
+	case "ac:task-list":
+		return conv.taskList(node)
+	case "table":
+		return conv.table(node)
+	case "ac:structured-macro":
+		return conv.macro(node)
+	default:
+		return conv.blocks(textMergedChildren(node))
+	}
+}
+
+// 转换一组行内节点
+func (conv *markdownConverter) inlines(nodes []*storage.Node) string {
+	var sb strings.Builder
+	for _, node := range nodes {
+		sb.WriteString(conv.inline(node))
+	}
+	return sb.String()
+}
+
+// 转换行内节点
+func (conv *markdownConverter) inline(node *storage.Node) string {
+	if node.Type == storage.TextNode {
+		return markdownEscapeReplacer.Replace(markdownSpaceRegexp.ReplaceAllString(node.Data, " "))
+	}
+
+	switch node.Name {
+	case "strong", "b":
+		return wrapInline(conv.inlines(textMergedChildren(node)), "**")
+	case "em", "i":
+		return wrapInline(conv.inlines(textMergedChildren(node)), "*")
+	case "s", "del", "strike":
+		return wrapInline(conv.inlines(textMergedChildren(node)), "~~")
+	case "code":
+		return codeSpan(node.Text())
+	case "br":
+		return "  \n"
+	case "a":
+		text := conv.inlines(textMergedChildren(node))
+		href := node.Attr("href")
+		if href == "" {
+			return text
+		}
+		return "[" + conv.linkText(text) + "](" + markdownLinkDestination(href) + ")"
+	case "ac:link":
+		return conv.link(node)
+	case "ac:image":
+		return conv.image(node)
+	case "ac:emoticon":
+		return ":" + node.Attr("ac:name") + ":"
+	case "time":
+		return node.Attr("datetime")
+	case "ac:structured-macro":
+		return conv.inlineMacro(node)
+	case "sub", "sup", "u":
+		return "<" + node.Name + ">" + conv.inlines(textMergedChildren(node)) + "</" + node.Name + ">"
+	default:
+		return conv.inlines(textMergedChildren(node))
+	}
+}
+
+// 转换行内宏
+func (conv *markdownConverter) inlineMacro(node *storage.Node) string {
+	switch node.Attr("ac:name") {
+	case "status":
+		return codeSpan(storage.Macro{Node: node}.Param("title"))
+	case "jira":
+		return storage.Macro{Node: node}.Param("key")
+	default:
+		return ""
+	}
+}
+
+// 转换块级宏
+func (conv *markdownConverter) macro(node *storage.Node) string {
+	name := node.Attr("ac:name")
+
+	if name == "code" || name == "noformat" {
+		body := ""
+		if plain := node.Child("ac:plain-text-body"); plain != nil {
+			body = plain.Text()
+		}
+		return markdownFence(storage.Macro{Node: node}.Param("language"), body)
+	}
+
+	body := ""
+	if rich := node.Child("ac:rich-text-body"); rich != nil {
+		body = conv.blocks(textMergedChildren(rich))
+	}
+
+	if kind, found := markdownAdmonitions[name]; found {
+		content := "[!" + kind + "]"
+		if title := strings.TrimSpace(storage.Macro{Node: node}.Param("title")); title != "" {
+			content += "\n**" + markdownEscapeReplacer.Replace(title) + "**"
+		}
+		if body != "" {
+			content += "\n" + body
+		}
+		return prefixLines(content, "> ")
+	}
+
+	//面板、展开等宏保留标题和内容
+	if title := strings.TrimSpace(storage.Macro{Node: node}.Param("title")); title != "" && body != "" {
+		return "**" + markdownEscapeReplacer.Replace(title) + "**\n\n" + body
+	}
+
+	return body
+}
+
+// 转换列表
+func (conv *markdownConverter) list(node *storage.Node) string {
+	var items []string
+	n := 0
+	for _, child := range node.Children {
+		if child.Name != "li" {
+			continue
+		}
+		n++
+
+		marker := "- "
+		if node.Name == "ol" {
+			marker = strconv.Itoa(n) + ". "
+		}
+
+		//紧跟在文本后的子列表不需要空行分隔
+		body := markdownNestedListRegexp.ReplaceAllString(conv.blocks(textMergedChildren(child)), "\n$1")
+		items = append(items, listItem(marker, body))
+	}
+
+	return strings.Join(items, "\n")
+}
+
+// 转换任务列表
+func (conv *markdownConverter) taskList(node *storage.Node) string {
+	var items []string
+	for _, task := range node.Children {
+		if task.Name != "ac:task" {
+			continue
+		}
+
+		marker := "- [ ] "
+		if status := task.Child("ac:task-status"); status != nil && strings.TrimSpace(status.Text()) == "complete" {
+			marker = "- [x] "
+		}
+
+		body := ""
+		if taskBody := task.Child("ac:task-body"); taskBody != nil {
+			body = conv.blocks(textMergedChildren(taskBody))
+		}
+
+		items = append(items, listItem(marker, body))
+	}
+
+	return strings.Join(items, "\n")
+}
+
+// 转换表格，第一行作为表头
+func (conv *markdownConverter) table(node *storage.Node) string {
+	var rows [][]string
+	var walk func(*storage.Node)
+	walk = func(n *storage.Node) {
+		for _, child := range n.Children {
+			switch child.Name {
+			case "tr":
+				var cells []string
+				for _, cell := range child.Children {
+					if cell.Name == "td" || cell.Name == "th" {
+						text := strings.TrimSpace(conv.blocks(textMergedChildren(cell)))
+						cells = append(cells, markdownTableEscaper.Replace(strings.Replace(text, "\n\n", "\n", -1)))
+					}
+				}
+				rows = append(rows, cells)
+			case "thead", "tbody", "tfoot":
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+
+	if len(rows) == 0 {
+		return ""
+	}
+
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+
+	var sb strings.Builder
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", cols) + "\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// 转换Confluence链接
+func (conv *markdownConverter) link(node *storage.Node) string {
+	text := ""
+	if body := node.Child("ac:plain-text-link-body"); body != nil {
+		text = markdownEscapeReplacer.Replace(body.Text())
+	} else if body := node.Child("ac:link-body"); body != nil {
+		text = conv.inlines(textMergedChildren(body))
+	}
+
+	anchor := node.Attr("ac:anchor")
+	href := ""
+
+	switch {
+	case node.Child("ri:page") != nil:
+		page := node.Child("ri:page")
+		title := page.Attr("ri:content-title")
+		if text == "" {
+			text = markdownEscapeReplacer.Replace(title)
+		}
+		href = conv.pageLink(page.Attr("ri:space-key"), title, anchor)
+	case node.Child("ri:attachment") != nil:
+		att := node.Child("ri:attachment")
+		if text == "" {
+			text = markdownEscapeReplacer.Replace(att.Attr("ri:filename"))
+		}
+		href = conv.attachmentLink(att)
+	case node.Child("ri:url") != nil:
+		href = node.Child("ri:url").Attr("ri:value")
+		if text == "" {
+			text = markdownEscapeReplacer.Replace(href)
+		}
+	case anchor != "":
+		href = "#" + anchor
+		if text == "" {
+			text = markdownEscapeReplacer.Replace(anchor)
+		}
+	default:
+		return text
+	}
+
+	if href == "" {
+		return text
+	}
+
+	return "[" + conv.linkText(text) + "](" + markdownLinkDestination(href) + ")"
+}
+
+// 转换Confluence图片
+func (conv *markdownConverter) image(node *storage.Node) string {
+	alt := node.Attr("ac:alt")
+	if alt == "" {
+		alt = node.Attr("ac:title")
+	}
+
+	src := ""
+	if att := node.Child("ri:attachment"); att != nil {
+		src = conv.attachmentLink(att)
+		if alt == "" {
+			alt = att.Attr("ri:filename")
+		}
+	} else if u := node.Child("ri:url"); u != nil {
+		src = u.Attr("ri:value")
+	}
+
+	return "![" + conv.linkText(markdownEscapeReplacer.Replace(alt)) + "](" + markdownLinkDestination(src) + ")"
+}
+
+// 页面引用的链接地址
+func (conv *markdownConverter) pageLink(spaceKey, title, anchor string) string {
+	if conv.opt.PageLink != nil {
+		return conv.opt.PageLink(spaceKey, title, anchor)
+	}
+
+	href := title + ".md"
+	if anchor != "" {
+		href += "#" + anchor
+	}
+	return href
+}
+
+// 附件引用的链接地址
+func (conv *markdownConverter) attachmentLink(att *storage.Node) string {
+	pageTitle := ""
+	if page := att.Child("ri:page"); page != nil {
+		pageTitle = page.Attr("ri:content-title")
+	}
+	filename := att.Attr("ri:filename")
+
+	if conv.opt.AttachmentLink != nil {
+		return conv.opt.AttachmentLink(pageTitle, filename)
+	}
+
+	if pageTitle != "" {
+		return pageTitle + "/" + filename
+	}
+	return filename
+}
+
+// 链接文本中不能包含换行
+func (conv *markdownConverter) linkText(text string) string {
+	return strings.TrimSpace(markdownLinkTextCleaner.Replace(text))
+}
+
+// 转换链接地址，包含空格等字符时进行转义
+func markdownLinkDestination(href string) string {
+	if !strings.ContainsAny(href, " ()<>") {
+		return href
+	}
+
+	u, err := url.Parse(href)
+	if err != nil || u.Scheme != "" {
+		return "<" + href + ">"
+	}
+
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E").Replace(href)
+}
+
+// 生成围栏代码块，围栏长度大于内容中最长的反引号序列
+func markdownFence(language, code string) string {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+
+	return fence + language + "\n" + strings.Trim(code, "\n") + "\n" + fence
+}
+
+// 为行内内容添加强调标记，标记放在首尾空白之内
+func wrapInline(text, mark string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+
+	leading := text[:strings.Index(text, trimmed)]
+	trailing := text[len(leading)+len(trimmed):]
+	return leading + mark + trimmed + mark + trailing
+}
+
+// 为每一行添加前缀
+func prefixLines(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		//只去掉空行的前缀空格，保留行尾表示硬换行的空格
+		if line == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 生成行内代码，使用比内容中最长的连续反引号更长的分隔符
+func codeSpan(text string) string {
+	if text == "" {
+		return ""
+	}
+
+	longest, run := 0, 0
+	for _, c := range text {
+		if c == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+
+	//内容首尾为反引号或首尾均为空格时，需要用空格隔开，解析时会去掉首尾各一个空格
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") ||
+		(strings.HasPrefix(text, " ") && strings.HasSuffix(text, " ") && strings.Trim(text, " ") != "") {
+		text = " " + text + " "
+	}
+
+	return fence + text + fence
+}
+
+// 生成列表项，后续行缩进到与内容对齐
+func listItem(marker, body string) string {
+	indent := strings.Repeat(" ", len(marker))
+
+	lines := strings.Split(body, "\n")
+	for i := range lines {
+		if i == 0 {
+			lines[i] = marker + lines[i]
+		} else if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+
+	return strings.TrimRight(strings.Join(lines, "\n"), " ")
+}
@@ -0,0 +1,51 @@
+package confluence
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 将testdata/storage中的Storage格式文件转换为Markdown，与同名的.md文件比较
+func TestStorageToMarkdownGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "storage", "*.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("没有找到测试文件")
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".xml")
+		t.Run(name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := StorageToMarkdown(string(data), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := strings.TrimSuffix(file, ".xml") + ".md"
+			if *updateGolden {
+				err = ioutil.WriteFile(golden, []byte(got), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("转换结果与%s不一致\n得到:\n%s\n期望:\n%s", golden, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,1435 @@
+package confluence
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-http/confluence/storage"
+)
+
+// Markdown转换为Storage格式的选项
+type MarkdownStorageOption struct {
+	// 将相对的Markdown文件链接（如../guide/install.md）解析为页面标题，
+	// 返回空字符串时保留为普通链接。为空时使用去掉.md后缀的文件名
+	PageTitle func(link string) string
+}
+
+// Markdown转换为Storage格式的结果
+type MarkdownStorageResult struct {
+	Storage     string
+	Attachments []string //引用的本地图片和文件，路径相对于Markdown文件，需要作为附件上传
+
+	//无法作为附件引用的本地文件：路径超出Markdown文件所在的目录，或文件名与其他附件相同。
+	//附件以文件名区分，同名的文件会互相覆盖
+	Errors []string
+}
+
+// GitHub提示块和admonition类型对应的Confluence宏
+var storageAdmonitionMacros = map[string]string{
+	"note":      "info",
+	"info":      "info",
+	"tip":       "tip",
+	"hint":      "tip",
+	"important": "note",
+	"warning":   "warning",
+	"caution":   "warning",
+	"danger":    "warning",
+}
+
+// 代码块语言对应的Confluence代码宏语言
+var storageCodeLanguages = map[string]string{
+	"sh":         "bash",
+	"shell":      "bash",
+	"zsh":        "bash",
+	"console":    "bash",
+	"python":     "py",
+	"javascript": "js",
+	"yaml":       "yml",
+	"html":       "xml",
+	"csharp":     "c#",
+	"cs":         "c#",
+	"c++":        "cpp",
+	"c":          "cpp",
+	"golang":     "go",
+	"ps1":        "powershell",
+	"rb":         "ruby",
+}
+
+var (
+	mdATXHeadingRegexp  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdThematicRegexp    = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdFenceRegexp       = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`]*?)[ \t]*$")
+	mdSetextRegexp      = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	mdQuoteRegexp       = regexp.MustCompile(`^ {0,3}> ?`)
+	mdListItemRegexp    = regexp.MustCompile(`^( {0,3})([-+*]|\d{1,9}[.)])([ \t]+|$)`)
+	mdTaskRegexp        = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+	mdTableDelimRegexp  = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdHTMLBlockRegexp   = regexp.MustCompile(`(?i)^ {0,3}<(?:/?(?:` + mdHTMLBlockTags + `)(?:[ \t/>]|$)|!--)`)
+	mdLinkDefRegexp     = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:[ \t]*<?([^ \t>]+)>?(?:[ \t]+["'(](.*)["')])?[ \t]*$`)
+	mdGitHubAlertRegexp = regexp.MustCompile(`^\[!([A-Za-z]+)\][ \t]*(.*)$`)
+	mdAdmonitionRegexp  = regexp.MustCompile(`^!!![ \t]+([A-Za-z]+)(?:[ \t]+"(.*)")?[ \t]*$`)
+	mdInlineHTMLRegexp  = regexp.MustCompile(`^(?:<[A-Za-z][A-Za-z0-9:-]*(?:\s+[A-Za-z_:][A-Za-z0-9_.:-]*(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?)*\s*/?>|</[A-Za-z][A-Za-z0-9:-]*\s*>|<!--[\s\S]*?-->)`)
+	mdAutolinkRegexp    = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*)>`)
+	mdBareURLRegexp     = regexp.MustCompile(`^https?://[^\s<]*[^\s<?!.,:*_~)'"]`)
+	mdEntityRegexp      = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9A-Fa-f]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	mdVoidHTMLTagRegexp = regexp.MustCompile(`(?i)<(` + mdVoidHTMLTags + `)(\s[^<>]*?)?\s*/?>`)
+	mdLinkSchemeRegexp  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:`)
+	mdPunctuationChars  = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+)
+
+// 开始HTML块的元素名，与CommonMark的块级元素一致
+const mdHTMLBlockTags = "address|article|aside|base|basefont|blockquote|body|caption|center|col|colgroup|dd|details|dialog|dir|div|dl|dt|" +
+	"fieldset|figcaption|figure|footer|form|frame|frameset|h[1-6]|head|header|hr|html|iframe|legend|li|link|main|menu|menuitem|" +
+	"nav|noframes|ol|optgroup|option|p|param|search|section|summary|table|tbody|td|tfoot|th|thead|title|tr|track|ul"
+
+// HTML中没有结束标签的空元素，在XHTML中需要自闭合
+const mdVoidHTMLTags = "area|base|br|col|embed|hr|img|input|link|meta|param|source|track|wbr"
+
+// 将Markdown（CommonMark及GFM表格、任务列表）转换为Storage格式
+//
+// 围栏代码块转换为code宏，GitHub提示块（> [!NOTE]）和admonition（!!! note）转换为信息/提示/注意/警告宏，
+// 指向其他Markdown文件的相对链接转换为页面链接，本地图片转换为附件引用并在结果中列出
+func MarkdownToStorage(markdown string, opt *MarkdownStorageOption) MarkdownStorageResult {
+	if opt == nil {
+		opt = &MarkdownStorageOption{}
+	}
+
+	lines := strings.Split(strings.Replace(markdown, "\r\n", "\n", -1), "\n")
+
+	conv := &storageConverter{
+		opt:      opt,
+		refs:     make(map[string]mdLinkRef),
+		attSeen:  make(map[string]bool),
+		attNames: make(map[string]string),
+	}
+	lines = conv.collectLinkRefs(lines)
+
+	var sb strings.Builder
+	for _, block := range parseMarkdownBlocks(lines) {
+		conv.writeBlock(&sb, block, false)
+	}
+
+	return MarkdownStorageResult{
+		Storage:     sb.String(),
+		Attachments: conv.attachments,
+		Errors:      conv.errors,
+	}
+}
+
+// 将Markdown发布为页面：options.Data为Markdown内容，转换为Storage格式后同步页面，
+// 并上传引用的本地图片和文件，baseDir为Markdown文件所在目录
+func (s *PageSync) SyncMarkdown(options *DrawModifyPageOption, baseDir string, opt *MarkdownStorageOption) (SyncResult, error) {
+	converted := MarkdownToStorage(options.Data, opt)
+	if len(converted.Errors) > 0 {
+		return SyncResult{}, fmt.Errorf("转换Markdown失败: %s", strings.Join(converted.Errors, "; "))
+	}
+
+	//先检查附件是否存在，以免页面已更新但附件上传失败
+	files := make([]string, 0, len(converted.Attachments))
+	for _, att := range converted.Attachments {
+		file := filepath.Join(baseDir, filepath.FromSlash(att))
+		if _, err := os.Stat(file); err != nil {
+			return SyncResult{}, fmt.Errorf("读取附件%s失败: %s", att, err)
+		}
+		files = append(files, file)
+	}
+
+	storageOptions := *options
+	storageOptions.Data = converted.Storage
+
+	result, err := s.Sync(&storageOptions)
+	if err != nil {
+		return result, err
+	}
+
+	//附件内容未变化时不重复上传
+	for _, file := range files {
+		_, _, err = s.Client.AttachmentUploadIfChanged(result.Content.Id, file, "")
+		if err != nil {
+			return result, fmt.Errorf("上传附件失败: %s", err)
+		}
+	}
+
+	return result, nil
+}
+
+// Markdown的块级元素
+type mdBlock struct {
+	kind     string //paragraph、heading、hr、code、quote、admonition、list、table、html
+	level    int    //标题级别
+	text     string //段落、标题的行内文本，代码块内容
+	info     string //代码块语言，提示块类型
+	title    string //提示块标题
+	children []*mdBlock
+	items    []*mdListItem
+	ordered  bool
+	start    int
+	tight    bool
+	task     bool
+	align    []string
+	rows     [][]string
+}
+
+// Markdown的列表项
+type mdListItem struct {
+	blocks  []*mdBlock
+	task    bool
+	checked bool
+}
+
+// 解析块级元素
+func parseMarkdownBlocks(lines []string) []*mdBlock {
+	var blocks []*mdBlock
+	var para []string
+
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, &mdBlock{kind: "paragraph", text: strings.Join(para, "\n")})
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+
+		//Setext标题
+		if len(para) > 0 {
+			if m := mdSetextRegexp.FindStringSubmatch(line); m != nil {
+				level := 2
+				if m[1][0] == '=' {
+					level = 1
+				}
+				blocks = append(blocks, &mdBlock{kind: "heading", level: level, text: strings.Join(para, "\n")})
+				para = nil
+				continue
+			}
+		}
+
+		if mdThematicRegexp.MatchString(line) {
+			flush()
+			blocks = append(blocks, &mdBlock{kind: "hr"})
+			continue
+		}
+
+		if m := mdATXHeadingRegexp.FindStringSubmatch(line); m != nil {
+			flush()
+			blocks = append(blocks, &mdBlock{kind: "heading", level: len(m[1]), text: m[2]})
+			continue
+		}
+
+		if m := mdFenceRegexp.FindStringSubmatch(line); m != nil {
+			flush()
+			block, next := parseFencedCode(lines, i, m)
+			blocks = append(blocks, block)
+			i = next
+			continue
+		}
+
+		//缩进代码块不能打断段落
+		if len(para) == 0 && mdIndent(line) >= 4 {
+			block, next := parseIndentedCode(lines, i)
+			blocks = append(blocks, block)
+			i = next
+			continue
+		}
+
+		if mdQuoteRegexp.MatchString(line) {
+			flush()
+			block, next := parseQuote(lines, i)
+			blocks = append(blocks, block)
+			i = next
+			continue
+		}
+
+		if m := mdAdmonitionRegexp.FindStringSubmatch(line); m != nil {
+			flush()
+			block, next := parseAdmonition(lines, i, m)
+			blocks = append(blocks, block)
+			i = next
+			continue
+		}
+
+		if m := mdListItemRegexp.FindStringSubmatch(line); m != nil && canStartList(m, len(para) > 0, line) {
+			flush()
+			block, next := parseList(lines, i)
+			blocks = append(blocks, block)
+			i = next
+			continue
+		}
+
+		//块级元素的HTML块可以打断段落
+		if mdHTMLBlockRegexp.MatchString(line) {
+			flush()
+			block, next := parseHTMLBlock(lines, i)
+			blocks = append(blocks, block)
+			i = next
+			continue
+		}
+
+		//GFM表格：表头行后紧跟分隔行
+		if len(para) == 0 && strings.Contains(line, "|") && i+1 < len(lines) && mdTableDelimRegexp.MatchString(lines[i+1]) {
+			block, next := parseTable(lines, i)
+			if block != nil {
+				blocks = append(blocks, block)
+				i = next
+				continue
+			}
+		}
+
+		para = append(para, strings.TrimLeft(line, " \t"))
+	}
+	flush()
+
+	return blocks
+}
+
+// 行是否开始一个新的块（用于判断懒惰续行）
+func startsMarkdownBlock(line string) bool {
+	if strings.TrimSpace(line) == "" {
+		return true
+	}
+	if mdThematicRegexp.MatchString(line) || mdATXHeadingRegexp.MatchString(line) ||
+		mdFenceRegexp.MatchString(line) || mdQuoteRegexp.MatchString(line) || mdAdmonitionRegexp.MatchString(line) ||
+		mdHTMLBlockRegexp.MatchString(line) {
+		return true
+	}
+	if m := mdListItemRegexp.FindStringSubmatch(line); m != nil && canStartList(m, true, line) {
+		return true
+	}
+	return false
+}
+
+// 列表项能否开始列表：打断段落时，有序列表必须从1开始，且列表项不能为空
+func canStartList(m []string, inParagraph bool, line string) bool {
+	if !inParagraph {
+		return true
+	}
+	if strings.TrimSpace(line[len(m[0]):]) == "" {
+		return false
+	}
+	marker := m[2]
+	if marker[0] >= '0' && marker[0] <= '9' {
+		return marker[:len(marker)-1] == "1"
+	}
+	return true
+}
+
+// 解析围栏代码块
+func parseFencedCode(lines []string, i int, m []string) (*mdBlock, int) {
+	indent := len(m[1])
+	fence := m[2]
+	info := strings.Fields(m[3])
+
+	block := &mdBlock{kind: "code"}
+	if len(info) > 0 {
+		block.info = info[0]
+	}
+
+	var code []string
+	j := i + 1
+	for ; j < len(lines); j++ {
+		trimmed := strings.TrimLeft(lines[j], " ")
+		if len(lines[j])-len(trimmed) <= 3 && strings.HasPrefix(trimmed, fence[:1]) &&
+			len(strings.TrimRight(trimmed, " ")) >= len(fence) && strings.Trim(strings.TrimRight(trimmed, " "), fence[:1]) == "" {
+			break
+		}
+
+		//去除与开始围栏相同的缩进
+		line := lines[j]
+		for k := 0; k < indent && strings.HasPrefix(line, " "); k++ {
+			line = line[1:]
+		}
+		code = append(code, line)
+	}
+
+	block.text = strings.Join(code, "\n")
+	return block, j
+}
+
+// 解析缩进代码块
+func parseIndentedCode(lines []string, i int) (*mdBlock, int) {
+	var code []string
+	j := i
+	for ; j < len(lines); j++ {
+		if mdIndent(lines[j]) >= 4 {
+			code = append(code, mdStripIndent(lines[j], 4))
+		} else if strings.TrimSpace(lines[j]) == "" {
+			code = append(code, "")
+		} else {
+			break
+		}
+	}
+
+	//去除末尾的空行
+	for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+		code = code[:len(code)-1]
+	}
+
+	return &mdBlock{kind: "code", text: strings.Join(code, "\n")}, j - 1
+}
+
+// 解析引用块，首行为[!TYPE]时作为提示块
+func parseQuote(lines []string, i int) (*mdBlock, int) {
+	var inner []string
+	j := i
+	for ; j < len(lines); j++ {
+		line := lines[j]
+		if loc := mdQuoteRegexp.FindStringIndex(line); loc != nil {
+			inner = append(inner, line[loc[1]:])
+			continue
+		}
+
+		//段落的懒惰续行
+		if len(inner) > 0 && strings.TrimSpace(inner[len(inner)-1]) != "" && !startsMarkdownBlock(line) {
+			inner = append(inner, line)
+			continue
+		}
+		break
+	}
+
+	block := &mdBlock{kind: "quote"}
+	if len(inner) > 0 {
+		if m := mdGitHubAlertRegexp.FindStringSubmatch(strings.TrimSpace(inner[0])); m != nil {
+			if _, found := storageAdmonitionMacros[strings.ToLower(m[1])]; found {
+				block.kind = "admonition"
+				block.info = strings.ToLower(m[1])
+				block.title = m[2]
+				inner = inner[1:]
+			}
+		}
+	}
+	block.children = parseMarkdownBlocks(inner)
+
+	return block, j - 1
+}
+
+// 解析!!!形式的admonition，内容为其后缩进4个空格的行
+func parseAdmonition(lines []string, i int, m []string) (*mdBlock, int) {
+	var inner []string
+	j := i + 1
+	for ; j < len(lines); j++ {
+		if mdIndent(lines[j]) >= 4 {
+			inner = append(inner, mdStripIndent(lines[j], 4))
+		} else if strings.TrimSpace(lines[j]) == "" {
+			inner = append(inner, "")
+		} else {
+			break
+		}
+	}
+
+	kind := strings.ToLower(m[1])
+	if _, found := storageAdmonitionMacros[kind]; !found {
+		kind = "note"
+	}
+
+	block := &mdBlock{kind: "admonition", info: kind, title: m[2]}
+	block.children = parseMarkdownBlocks(inner)
+
+	return block, j - 1
+}
+
+// 解析列表
+func parseList(lines []string, i int) (*mdBlock, int) {
+	first := mdListItemRegexp.FindStringSubmatch(lines[i])
+	marker := first[2]
+	ordered := marker[0] >= '0' && marker[0] <= '9'
+
+	block := &mdBlock{kind: "list", ordered: ordered, tight: true}
+	if ordered {
+		block.start, _ = strconv.Atoi(marker[:len(marker)-1])
+	}
+
+	//是否与列表第一项使用相同的标记
+	sameList := func(m []string) bool {
+		mk := m[2]
+		if ordered {
+			return mk[0] >= '0' && mk[0] <= '9' && mk[len(mk)-1] == marker[len(marker)-1]
+		}
+		return mk == marker
+	}
+
+	j := i
+	for j < len(lines) {
+		m := mdListItemRegexp.FindStringSubmatch(lines[j])
+		if m == nil || !sameList(m) {
+			break
+		}
+
+		//内容的缩进位置（列数），标记后的空白超过4列时内容为缩进代码块，只去除1列
+		markerEnd := len(m[1]) + len(m[2])
+		offset := mdIndent(m[1] + strings.Repeat(" ", len(m[2])) + m[3])
+		itemLines := []string{lines[j][len(m[0]):]}
+		if offset-markerEnd > 4 || m[3] == "" {
+			offset = markerEnd + 1
+			itemLines[0] = mdStripIndent(lines[j][markerEnd:], 1)
+		}
+
+		j++
+		for ; j < len(lines); j++ {
+			line := lines[j]
+			if strings.TrimSpace(line) == "" {
+				itemLines = append(itemLines, "")
+				continue
+			}
+
+			if mdIndent(line) >= offset {
+				itemLines = append(itemLines, mdStripIndent(line, offset))
+				continue
+			}
+
+			//同级的下一个列表项
+			if mdListItemRegexp.MatchString(line) {
+				break
+			}
+
+			//段落的懒惰续行
+			last := itemLines[len(itemLines)-1]
+			if strings.TrimSpace(last) != "" && !startsMarkdownBlock(line) && !mdFenceRegexp.MatchString(itemLines[0]) {
+				itemLines = append(itemLines, strings.TrimLeft(line, " \t"))
+				continue
+			}
+			break
+		}
+
+		//去除末尾的空行，末尾空行后还有同一列表的列表项时为松散列表
+		trailingBlank := false
+		for len(itemLines) > 1 && strings.TrimSpace(itemLines[len(itemLines)-1]) == "" {
+			itemLines = itemLines[:len(itemLines)-1]
+			trailingBlank = true
+		}
+		if trailingBlank && j < len(lines) {
+			if next := mdListItemRegexp.FindStringSubmatch(lines[j]); next != nil && sameList(next) {
+				block.tight = false
+			}
+		}
+
+		item := &mdListItem{}
+		if tm := mdTaskRegexp.FindStringSubmatch(itemLines[0]); tm != nil {
+			item.task = true
+			item.checked = tm[1] != " "
+			itemLines[0] = itemLines[0][len(tm[0]):]
+		}
+
+		//列表项内部的空行分隔了多个块时为松散列表
+		for k := 1; k < len(itemLines)-1; k++ {
+			if strings.TrimSpace(itemLines[k]) == "" && !insideFence(itemLines[:k]) {
+				block.tight = false
+			}
+		}
+
+		item.blocks = parseMarkdownBlocks(itemLines)
+		block.items = append(block.items, item)
+	}
+
+	block.task = len(block.items) > 0 && block.items[0].task
+
+	return block, j - 1
+}
+
+// 行首缩进的列数，制表符按4列的制表位展开
+func mdIndent(line string) int {
+	col := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			col++
+		case '\t':
+			col += 4 - col%4
+		default:
+			return col
+		}
+	}
+	return col
+}
+
+// 去除行首n列的缩进，只展开被部分去除的制表符，其余内容保持不变
+func mdStripIndent(line string, n int) string {
+	col := 0
+	for i := 0; i < len(line); i++ {
+		if col >= n {
+			return line[i:]
+		}
+		switch line[i] {
+		case ' ':
+			col++
+		case '\t':
+			col += 4 - col%4
+			if col > n {
+				return strings.Repeat(" ", col-n) + line[i+1:]
+			}
+		default:
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+// 行之后是否处于未闭合的围栏代码块中
+func insideFence(lines []string) bool {
+	fence := ""
+	for _, line := range lines {
+		m := mdFenceRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if fence == "" {
+			fence = m[2]
+		} else if strings.HasPrefix(m[2], fence[:1]) && len(m[2]) >= len(fence) && m[3] == "" {
+			fence = ""
+		}
+	}
+	return fence != ""
+}
+
+// 解析HTML块，直到空行结束
+func parseHTMLBlock(lines []string, i int) (*mdBlock, int) {
+	var html []string
+	j := i
+	for ; j < len(lines) && strings.TrimSpace(lines[j]) != ""; j++ {
+		html = append(html, lines[j])
+	}
+
+	return &mdBlock{kind: "html", text: strings.Join(html, "\n")}, j - 1
+}
+
+// Storage格式是XHTML，将HTML中的空元素改为自闭合形式
+func closeVoidHTMLTags(html string) string {
+	return mdVoidHTMLTagRegexp.ReplaceAllString(html, "<$1$2/>")
+}
+
+// 解析GFM表格
+func parseTable(lines []string, i int) (*mdBlock, int) {
+	header := splitTableRow(lines[i])
+	delims := splitTableRow(lines[i+1])
+	if len(header) != len(delims) {
+		return nil, i
+	}
+
+	block := &mdBlock{kind: "table"}
+	for _, d := range delims {
+		d = strings.TrimSpace(d)
+		switch {
+		case strings.HasPrefix(d, ":") && strings.HasSuffix(d, ":"):
+			block.align = append(block.align, "center")
+		case strings.HasSuffix(d, ":"):
+			block.align = append(block.align, "right")
+		case strings.HasPrefix(d, ":"):
+			block.align = append(block.align, "left")
+		default:
+			block.align = append(block.align, "")
+		}
+	}
+
+	block.rows = append(block.rows, header)
+
+	j := i + 2
+	for ; j < len(lines); j++ {
+		if strings.TrimSpace(lines[j]) == "" || startsMarkdownBlock(lines[j]) {
+			break
+		}
+
+		row := splitTableRow(lines[j])
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		block.rows = append(block.rows, row[:len(header)])
+	}
+
+	return block, j - 1
+}
+
+// 拆分表格行，忽略首尾的竖线和转义的竖线
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	inCode := false
+	for k := 0; k < len(line); k++ {
+		c := line[k]
+		switch {
+		case c == '\\' && k+1 < len(line) && line[k+1] == '|':
+			cell.WriteByte('|')
+			k++
+		case c == '`':
+			inCode = !inCode
+			cell.WriteByte(c)
+		case c == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(c)
+		}
+	}
+	cells = append(cells, strings.TrimSpace(cell.String()))
+
+	return cells
+}
+
+// 链接引用定义
+type mdLinkRef struct {
+	href  string
+	title string
+}
+
+// Markdown到Storage格式的转换器
+type storageConverter struct {
+	opt         *MarkdownStorageOption
+	refs        map[string]mdLinkRef
+	attachments []string
+	attSeen     map[string]bool
+	attNames    map[string]string //附件文件名对应的路径
+	errors      []string
+	taskId      int
+}
+
+// 收集并移除链接引用定义（[ref]: url "title"），围栏代码块中的内容除外
+func (conv *storageConverter) collectLinkRefs(lines []string) []string {
+	var out []string
+	fence := ""
+	for _, line := range lines {
+		if m := mdFenceRegexp.FindStringSubmatch(line); m != nil {
+			if fence == "" {
+				fence = m[2]
+			} else if strings.HasPrefix(m[2], fence[:1]) && len(m[2]) >= len(fence) && m[3] == "" {
+				fence = ""
+			}
+		}
+
+		if fence == "" {
+			if m := mdLinkDefRegexp.FindStringSubmatch(line); m != nil {
+				label := normalizeLinkLabel(m[1])
+				if _, found := conv.refs[label]; !found {
+					conv.refs[label] = mdLinkRef{href: m[2], title: m[3]}
+				}
+				continue
+			}
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// 链接引用的标签不区分大小写并合并空白
+func normalizeLinkLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// 记录需要上传的附件，返回附件文件名。路径超出Markdown文件所在的目录时返回false
+func (conv *storageConverter) addAttachment(file string) (string, bool) {
+	file = path.Clean(file)
+	if file == ".." || strings.HasPrefix(file, "../") {
+		conv.errors = append(conv.errors, fmt.Sprintf("附件%s不在Markdown文件所在的目录中", file))
+		return "", false
+	}
+
+	name := path.Base(file)
+	if !conv.attSeen[file] {
+		conv.attSeen[file] = true
+		if other, found := conv.attNames[name]; found {
+			conv.errors = append(conv.errors, fmt.Sprintf("附件%s与%s的文件名相同", file, other))
+		} else {
+			conv.attNames[name] = file
+			conv.attachments = append(conv.attachments, file)
+		}
+	}
+	return name, true
+}
+
+// 输出块级元素，tight为紧凑列表项时段落不输出<p>
+func (conv *storageConverter) writeBlock(sb *strings.Builder, block *mdBlock, tight bool) {
+	switch block.kind {
+	case "paragraph":
+		if tight {
+			sb.WriteString(conv.inline(block.text))
+		} else {
+			sb.WriteString("<p>" + conv.inline(block.text) + "</p>")
+		}
+	case "heading":
+		tag := "h" + strconv.Itoa(block.level)
+		sb.WriteString("<" + tag + ">" + conv.inline(strings.TrimSpace(block.text)) + "</" + tag + ">")
+	case "hr":
+		sb.WriteString("<hr/>")
+	case "code":
+		sb.WriteString(`<ac:structured-macro ac:name="code">`)
+		if lang := strings.ToLower(block.info); lang != "" {
+			if mapped, found := storageCodeLanguages[lang]; found {
+				lang = mapped
+			}
+			sb.WriteString(`<ac:parameter ac:name="language">` + storage.EscapeAttr(lang) + `</ac:parameter>`)
+		}
+		sb.WriteString("<ac:plain-text-body>" + storage.NewCDATA(block.text).String() + "</ac:plain-text-body></ac:structured-macro>")
+	case "quote":
+		sb.WriteString("<blockquote>")
+		for _, child := range block.children {
+			conv.writeBlock(sb, child, false)
+		}
+		sb.WriteString("</blockquote>")
+	case "admonition":
+		sb.WriteString(`<ac:structured-macro ac:name="` + storageAdmonitionMacros[block.info] + `">`)
+		if block.title != "" {
+			sb.WriteString(`<ac:parameter ac:name="title">` + storage.EscapeText(block.title) + `</ac:parameter>`)
+		}
+		sb.WriteString("<ac:rich-text-body>")
+		for _, child := range block.children {
+			conv.writeBlock(sb, child, false)
+		}
+		sb.WriteString("</ac:rich-text-body></ac:structured-macro>")
+	case "list":
+		conv.writeList(sb, block)
+	case "table":
+		conv.writeTable(sb, block)
+	case "html":
+		//HTML块在空行处结束，可能只包含元素的一部分，无法作为Storage格式解析时作为文本输出
+		html := closeVoidHTMLTags(block.text)
+		if _, err := storage.Parse(html); err != nil {
+			sb.WriteString("<p>" + storage.EscapeText(block.text) + "</p>")
+		} else {
+			sb.WriteString(html)
+		}
+	}
+}
+
+// 输出列表，任务列表输出为ac:task-list
+func (conv *storageConverter) writeList(sb *strings.Builder, block *mdBlock) {
+	if block.task {
+		sb.WriteString("<ac:task-list>")
+		for _, item := range block.items {
+			conv.taskId++
+			status := "incomplete"
+			if item.checked {
+				status = "complete"
+			}
+			sb.WriteString("<ac:task><ac:task-id>" + strconv.Itoa(conv.taskId) + "</ac:task-id>")
+			sb.WriteString("<ac:task-status>" + status + "</ac:task-status><ac:task-body>")
+			for _, child := range item.blocks {
+				conv.writeBlock(sb, child, block.tight)
+			}
+			sb.WriteString("</ac:task-body></ac:task>")
+		}
+		sb.WriteString("</ac:task-list>")
+		return
+	}
+
+	tag := "ul"
+	if block.ordered {
+		tag = "ol"
+	}
+	sb.WriteString("<" + tag)
+	if block.ordered && block.start != 1 {
+		sb.WriteString(` start="` + strconv.Itoa(block.start) + `"`)
+	}
+	sb.WriteString(">")
+	for _, item := range block.items {
+		sb.WriteString("<li>")
+		for _, child := range item.blocks {
+			conv.writeBlock(sb, child, block.tight)
+		}
+		sb.WriteString("</li>")
+	}
+	sb.WriteString("</" + tag + ">")
+}
+
+// 输出表格，第一行为表头
+func (conv *storageConverter) writeTable(sb *strings.Builder, block *mdBlock) {
+	sb.WriteString("<table><tbody>")
+	for i, row := range block.rows {
+		tag := "td"
+		if i == 0 {
+			tag = "th"
+		}
+
+		sb.WriteString("<tr>")
+		for j, cell := range row {
+			sb.WriteString("<" + tag)
+			if block.align[j] != "" {
+				sb.WriteString(` style="text-align: ` + block.align[j] + `;"`)
+			}
+			sb.WriteString(">" + conv.inline(cell) + "</" + tag + ">")
+		}
+		sb.WriteString("</tr>")
+	}
+	sb.WriteString("</tbody></table>")
+}
+
+// 行内元素
+type mdInline struct {
+	kind     string //text、raw、delim、em、strong、del
+	text     string //text为未转义的文本，raw为已转换的Storage片段
+	children []*mdInline
+
+	delim    byte
+	count    int
+	origin   int
+	canOpen  bool
+	canClose bool
+}
+
+// 转换行内元素
+func (conv *storageConverter) inline(text string) string {
+	var sb strings.Builder
+	writeMdInlines(&sb, conv.parseInlines(text))
+	return sb.String()
+}
+
+// 输出行内元素
+func writeMdInlines(sb *strings.Builder, nodes []*mdInline) {
+	for _, node := range nodes {
+		switch node.kind {
+		case "text":
+			sb.WriteString(storage.EscapeText(node.text))
+		case "raw":
+			sb.WriteString(node.text)
+		case "delim":
+			sb.WriteString(storage.EscapeText(strings.Repeat(string(node.delim), node.count)))
+		case "em", "strong", "del":
+			tag := map[string]string{"em": "em", "strong": "strong", "del": "s"}[node.kind]
+			sb.WriteString("<" + tag + ">")
+			writeMdInlines(sb, node.children)
+			sb.WriteString("</" + tag + ">")
+		}
+	}
+}
+
+// 行内元素的纯文本，用于图片的alt
+func mdInlinesText(nodes []*mdInline) string {
+	var sb strings.Builder
+	for _, node := range nodes {
+		switch node.kind {
+		case "text":
+			sb.WriteString(node.text)
+		case "delim":
+			sb.WriteString(strings.Repeat(string(node.delim), node.count))
+		case "em", "strong", "del":
+			sb.WriteString(mdInlinesText(node.children))
+		}
+	}
+	return sb.String()
+}
+
+// 解析行内元素
+func (conv *storageConverter) parseInlines(text string) []*mdInline {
+	var nodes []*mdInline
+	var buf strings.Builder
+
+	flush := func() {
+		if buf.Len() > 0 {
+			nodes = append(nodes, &mdInline{kind: "text", text: buf.String()})
+			buf.Reset()
+		}
+	}
+	raw := func(s string) {
+		flush()
+		nodes = append(nodes, &mdInline{kind: "raw", text: s})
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && text[i+1] == '\n':
+			raw("<br/>")
+			i += 2
+			continue
+		case c == '\\' && i+1 < len(text) && strings.IndexByte(mdPunctuationChars, text[i+1]) >= 0:
+			buf.WriteByte(text[i+1])
+			i += 2
+			continue
+		case c == '\n':
+			//行尾两个以上空格为硬换行
+			s := buf.String()
+			trimmed := strings.TrimRight(s, " ")
+			buf.Reset()
+			buf.WriteString(trimmed)
+			if len(s)-len(trimmed) >= 2 {
+				raw("<br/>")
+			} else {
+				buf.WriteByte('\n')
+			}
+			i++
+			for i < len(text) && text[i] == ' ' {
+				i++
+			}
+			continue
+		case c == '`':
+			if code, n := parseCodeSpan(text[i:]); n > 0 {
+				raw("<code>" + storage.EscapeText(code) + "</code>")
+				i += n
+				continue
+			}
+			//未闭合的反引号串原样输出
+			n := 0
+			for i+n < len(text) && text[i+n] == '`' {
+				n++
+			}
+			buf.WriteString(text[i : i+n])
+			i += n
+			continue
+		case c == '!' && i+1 < len(text) && text[i+1] == '[':
+			if out, n := conv.parseLink(text, i+1, true); n > 0 {
+				raw(out)
+				i = i + 1 + n
+				continue
+			}
+		case c == '[':
+			if out, n := conv.parseLink(text, i, false); n > 0 {
+				raw(out)
+				i += n
+				continue
+			}
+		case c == '<':
+			if m := mdAutolinkRegexp.FindStringSubmatch(text[i:]); m != nil {
+				raw(`<a href="` + storage.EscapeAttr(m[1]) + `">` + storage.EscapeText(m[1]) + "</a>")
+				i += len(m[0])
+				continue
+			}
+			if m := mdInlineHTMLRegexp.FindString(text[i:]); m != "" {
+				raw(closeVoidHTMLTags(m))
+				i += len(m)
+				continue
+			}
+		case c == '&':
+			if m := mdEntityRegexp.FindString(text[i:]); m != "" {
+				raw(m)
+				i += len(m)
+				continue
+			}
+		case c == 'h' && (i == 0 || !isMdWordChar(text[i-1])):
+			if m := mdBareURLRegexp.FindString(text[i:]); m != "" {
+				raw(`<a href="` + storage.EscapeAttr(m) + `">` + storage.EscapeText(m) + "</a>")
+				i += len(m)
+				continue
+			}
+		case c == '*' || c == '_' || c == '~':
+			n := 0
+			for i+n < len(text) && text[i+n] == c {
+				n++
+			}
+			flush()
+			nodes = append(nodes, newMdDelimiter(text, i, n))
+			i += n
+			continue
+		}
+
+		buf.WriteByte(c)
+		i++
+	}
+	flush()
+
+	return processMdEmphasis(nodes)
+}
+
+// 是否为单词字符
+func isMdWordChar(c byte) bool {
+	return c >= 0x80 || c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// 根据前后字符判断强调标记能否开始或结束强调
+func newMdDelimiter(text string, i, n int) *mdInline {
+	c := text[i]
+	before, after := byte(' '), byte(' ')
+	if i > 0 {
+		before = text[i-1]
+	}
+	if i+n < len(text) {
+		after = text[i+n]
+	}
+
+	isSpace := func(b byte) bool { return b == ' ' || b == '\n' || b == '\t' }
+	isPunct := func(b byte) bool { return strings.IndexByte(mdPunctuationChars, b) >= 0 }
+
+	left := !isSpace(after) && (!isPunct(after) || isSpace(before) || isPunct(before))
+	right := !isSpace(before) && (!isPunct(before) || isSpace(after) || isPunct(after))
+
+	node := &mdInline{kind: "delim", delim: c, count: n, origin: n}
+	if c == '_' {
+		node.canOpen = left && (!right || isPunct(before))
+		node.canClose = right && (!left || isPunct(after))
+	} else {
+		node.canOpen = left
+		node.canClose = right
+	}
+	return node
+}
+
+// 按CommonMark的规则匹配强调标记
+func processMdEmphasis(nodes []*mdInline) []*mdInline {
+	for c := 0; c < len(nodes); c++ {
+		closer := nodes[c]
+		if closer.kind != "delim" || !closer.canClose || closer.count == 0 {
+			continue
+		}
+
+		//向前查找匹配的开始标记
+		o := -1
+		for k := c - 1; k >= 0; k-- {
+			opener := nodes[k]
+			if opener.kind != "delim" || opener.delim != closer.delim || !opener.canOpen || opener.count == 0 {
+				continue
+			}
+			if closer.delim == '~' {
+				if opener.count != closer.count {
+					continue
+				}
+			} else if (opener.canClose || closer.canOpen) && (opener.origin+closer.origin)%3 == 0 &&
+				(opener.origin%3 != 0 || closer.origin%3 != 0) {
+				continue
+			}
+			o = k
+			break
+		}
+		if o < 0 {
+			continue
+		}
+
+		opener := nodes[o]
+		var wrapped *mdInline
+		if closer.delim == '~' {
+			wrapped = &mdInline{kind: "del"}
+			opener.count, closer.count = 0, 0
+		} else {
+			use := 1
+			kind := "em"
+			if opener.count >= 2 && closer.count >= 2 {
+				use = 2
+				kind = "strong"
+			}
+			wrapped = &mdInline{kind: kind}
+			opener.count -= use
+			closer.count -= use
+		}
+		wrapped.children = append(wrapped.children, nodes[o+1:c]...)
+
+		rest := append([]*mdInline{wrapped}, nodes[c:]...)
+		nodes = append(nodes[:o+1], rest...)
+		c = o + 1
+
+		if opener.count == 0 {
+			nodes = append(nodes[:o], nodes[o+1:]...)
+			c--
+		}
+
+		//结束标记仍有剩余时继续用它匹配
+		if closer.count > 0 {
+			c--
+		}
+	}
+
+	//移除用尽的标记，未匹配的作为普通文本
+	var out []*mdInline
+	for _, node := range nodes {
+		if node.kind == "delim" && node.count == 0 {
+			continue
+		}
+		out = append(out, node)
+	}
+	return out
+}
+
+// 解析代码段，返回代码内容和消耗的长度
+func parseCodeSpan(text string) (string, int) {
+	n := 0
+	for n < len(text) && text[n] == '`' {
+		n++
+	}
+
+	for i := n; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		m := 0
+		for i+m < len(text) && text[i+m] == '`' {
+			m++
+		}
+		if m == n {
+			code := strings.Replace(text[n:i], "\n", " ", -1)
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			return code, i + m
+		}
+		i += m
+	}
+	return "", 0
+}
+
+// 查找与text[i]处'['匹配的']'
+func findLinkLabelEnd(text string, i int) int {
+	depth := 0
+	for k := i; k < len(text); k++ {
+		switch text[k] {
+		case '\\':
+			k++
+		case '`':
+			if _, n := parseCodeSpan(text[k:]); n > 0 {
+				k += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return k
+			}
+		}
+	}
+	return -1
+}
+
+// 解析链接目标和标题：(dest "title")
+func parseLinkDestination(text string) (href, title string, n int) {
+	if !strings.HasPrefix(text, "(") {
+		return "", "", 0
+	}
+
+	k := 1
+	for k < len(text) && (text[k] == ' ' || text[k] == '\n') {
+		k++
+	}
+
+	if k < len(text) && text[k] == '<' {
+		end := strings.IndexByte(text[k:], '>')
+		if end < 0 {
+			return "", "", 0
+		}
+		href = text[k+1 : k+end]
+		k += end + 1
+	} else {
+		start := k
+		depth := 0
+	dest:
+		for ; k < len(text); k++ {
+			switch text[k] {
+			case '\\':
+				k++
+			case '(':
+				depth++
+			case ')':
+				if depth == 0 {
+					break dest
+				}
+				depth--
+			case ' ', '\n':
+				break dest
+			}
+		}
+		if k > len(text) {
+			k = len(text)
+		}
+		href = unescapeMarkdown(text[start:k])
+	}
+
+	for k < len(text) && (text[k] == ' ' || text[k] == '\n') {
+		k++
+	}
+
+	if k < len(text) && (text[k] == '"' || text[k] == '\'' || text[k] == '(') {
+		closing := text[k]
+		if closing == '(' {
+			closing = ')'
+		}
+		end := strings.IndexByte(text[k+1:], closing)
+		if end < 0 {
+			return "", "", 0
+		}
+		title = unescapeMarkdown(text[k+1 : k+1+end])
+		k += end + 2
+		for k < len(text) && (text[k] == ' ' || text[k] == '\n') {
+			k++
+		}
+	}
+
+	if k >= len(text) || text[k] != ')' {
+		return "", "", 0
+	}
+	return href, title, k + 1
+}
+
+// 去除反斜杠转义
+func unescapeMarkdown(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(mdPunctuationChars, s[i+1]) >= 0 {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// 解析text[i]处的链接或图片，返回转换后的Storage片段和消耗的长度
+func (conv *storageConverter) parseLink(text string, i int, image bool) (string, int) {
+	end := findLinkLabelEnd(text, i)
+	if end < 0 {
+		return "", 0
+	}
+	label := text[i+1 : end]
+	rest := text[end+1:]
+
+	var ref mdLinkRef
+	n := 0
+	if href, title, m := parseLinkDestination(rest); m > 0 {
+		ref = mdLinkRef{href: href, title: title}
+		n = end + 1 - i + m
+	} else {
+		//引用链接：[text][ref]、[text][]、[text]
+		key := label
+		n = end + 1 - i
+		if strings.HasPrefix(rest, "[") {
+			if refEnd := strings.IndexByte(rest, ']'); refEnd > 0 {
+				if rest[1:refEnd] != "" {
+					key = rest[1:refEnd]
+				}
+				n += refEnd + 1
+			}
+		}
+
+		found := false
+		ref, found = conv.refs[normalizeLinkLabel(key)]
+		if !found {
+			return "", 0
+		}
+	}
+
+	if image {
+		return conv.renderImage(mdInlinesText(conv.parseInlines(label)), ref), n
+	}
+
+	//链接中不能嵌套链接
+	inner := conv.parseInlines(label)
+	return conv.renderLink(inner, ref), n
+}
+
+// 是否为本地的相对路径
+func isLocalLink(href string) bool {
+	return href != "" && !mdLinkSchemeRegexp.MatchString(href) && !strings.HasPrefix(href, "//") &&
+		!strings.HasPrefix(href, "/") && !strings.HasPrefix(href, "#")
+}
+
+// 本地链接对应的附件文件名，不是本地链接或无法作为附件时返回false
+func (conv *storageConverter) localAttachment(href string) (string, bool) {
+	if !isLocalLink(href) {
+		return "", false
+	}
+	return conv.addAttachment(unescapeURLPath(href))
+}
+
+// 输出图片，本地图片作为附件引用
+func (conv *storageConverter) renderImage(alt string, ref mdLinkRef) string {
+	var sb strings.Builder
+	sb.WriteString("<ac:image")
+	if alt != "" {
+		sb.WriteString(` ac:alt="` + storage.EscapeAttr(alt) + `"`)
+	}
+	if ref.title != "" {
+		sb.WriteString(` ac:title="` + storage.EscapeAttr(ref.title) + `"`)
+	}
+	sb.WriteString(">")
+
+	if file, ok := conv.localAttachment(ref.href); ok {
+		sb.WriteString(`<ri:attachment ri:filename="` + storage.EscapeAttr(file) + `"/>`)
+	} else {
+		sb.WriteString(`<ri:url ri:value="` + storage.EscapeAttr(ref.href) + `"/>`)
+	}
+	sb.WriteString("</ac:image>")
+
+	return sb.String()
+}
+
+// 输出链接，指向Markdown文件的相对链接作为页面链接，其他本地文件作为附件链接
+func (conv *storageConverter) renderLink(inner []*mdInline, ref mdLinkRef) string {
+	var body strings.Builder
+	writeMdInlines(&body, inner)
+
+	href := ref.href
+	anchor := ""
+	if k := strings.IndexByte(href, '#'); k >= 0 {
+		href, anchor = href[:k], href[k+1:]
+	}
+
+	var resource string
+	switch {
+	case href == "" && anchor != "":
+		//页面内锚点
+	case isLocalLink(href) && strings.HasSuffix(strings.ToLower(href), ".md"):
+		title := conv.pageTitle(unescapeURLPath(href))
+		if title == "" {
+			return `<a href="` + storage.EscapeAttr(ref.href) + `">` + body.String() + "</a>"
+		}
+		resource = `<ri:page ri:content-title="` + storage.EscapeAttr(title) + `"/>`
+	case isLocalLink(href):
+		file, ok := conv.localAttachment(href)
+		if !ok {
+			return `<a href="` + storage.EscapeAttr(ref.href) + `">` + body.String() + "</a>"
+		}
+		resource = `<ri:attachment ri:filename="` + storage.EscapeAttr(file) + `"/>`
+		anchor = ""
+	default:
+		var sb strings.Builder
+		sb.WriteString(`<a href="` + storage.EscapeAttr(ref.href) + `"`)
+		if ref.title != "" {
+			sb.WriteString(` title="` + storage.EscapeAttr(ref.title) + `"`)
+		}
+		sb.WriteString(">" + body.String() + "</a>")
+		return sb.String()
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<ac:link")
+	if anchor != "" {
+		sb.WriteString(` ac:anchor="` + storage.EscapeAttr(anchor) + `"`)
+	}
+	sb.WriteString(">" + resource)
+
+	//纯文本的链接使用ac:plain-text-link-body，带格式的使用ac:link-body
+	plain := true
+	for _, node := range inner {
+		if node.kind != "text" && node.kind != "delim" {
+			plain = false
+		}
+	}
+	if plain {
+		sb.WriteString("<ac:plain-text-link-body>" + storage.NewCDATA(mdInlinesText(inner)).String() + "</ac:plain-text-link-body>")
+	} else {
+		sb.WriteString("<ac:link-body>" + body.String() + "</ac:link-body>")
+	}
+	sb.WriteString("</ac:link>")
+
+	return sb.String()
+}
+
+// 解析页面链接对应的标题
+func (conv *storageConverter) pageTitle(link string) string {
+	if conv.opt.PageTitle != nil {
+		return conv.opt.PageTitle(link)
+	}
+
+	name := path.Base(link)
+	return name[:len(name)-len(path.Ext(name))]
+}
+
+// 还原链接中的%编码
+func unescapeURLPath(href string) string {
+	if k := strings.IndexAny(href, "?"); k >= 0 {
+		href = href[:k]
+	}
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		return unescaped
+	}
+	return href
+}
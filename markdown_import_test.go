@@ -0,0 +1,80 @@
+package confluence
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-http/confluence/storage"
+)
+
+var updateGolden = flag.Bool("update", false, "更新testdata中的期望输出")
+
+// 将testdata/markdown中的Markdown文件转换为Storage格式，与同名的.xml文件比较
+func TestMarkdownToStorageGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "markdown", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("没有找到测试文件")
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".md")
+		t.Run(name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := MarkdownToStorage(string(data), nil)
+			if _, err := storage.Parse(result.Storage); err != nil {
+				t.Errorf("转换结果不是有效的Storage格式: %s", err)
+			}
+
+			got := result.Storage + "\n"
+			for _, att := range result.Attachments {
+				got += "attachment: " + att + "\n"
+			}
+
+			golden := strings.TrimSuffix(file, ".md") + ".xml"
+			if *updateGolden {
+				err = ioutil.WriteFile(golden, []byte(got), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("转换结果与%s不一致\n得到:\n%s\n期望:\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestMarkdownToStorageAttachmentErrors(t *testing.T) {
+	markdown := "![a](img/logo.png) ![b](other/logo.png) [c](../secret.txt) ![d](img/../img/logo.png)"
+	result := MarkdownToStorage(markdown, nil)
+
+	if !reflect.DeepEqual(result.Attachments, []string{"img/logo.png"}) {
+		t.Errorf("附件为%v", result.Attachments)
+	}
+	if len(result.Errors) != 2 {
+		t.Fatalf("错误为%v", result.Errors)
+	}
+	if !strings.Contains(result.Errors[0], "other/logo.png") || !strings.Contains(result.Errors[1], "../secret.txt") {
+		t.Errorf("错误为%v", result.Errors)
+	}
+	if strings.Contains(result.Storage, "secret.txt\"/>") {
+		t.Errorf("目录外的文件不应作为附件: %s", result.Storage)
+	}
+}
@@ -0,0 +1,174 @@
+package confluence
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 页面改名的选项
+type RenameOption struct {
+	DryRun bool //只查找需要修改的引用，不修改页面标题和引用
+
+	//扫描空间的所有页面和博客查找引用，而不是通过CQL搜索包含旧标题的页面。服务器不支持CQL搜索时总是扫描。
+	//
+	//CQL搜索（text ~ "旧标题"）只匹配页面的正文文本，链接文本与标题不同的链接，
+	//以及宏参数中的ri:page引用（如include、children宏）都搜索不到，需要完整修改时应开启
+	FullScan bool
+	Spaces   []string //扫描的空间，为空时只扫描页面所在的空间
+}
+
+// 页面改名的结果
+type RenameReport struct {
+	PageId   string
+	SpaceKey string
+	OldTitle string
+	NewTitle string
+	Renamed  bool //是否修改了页面标题，DryRun时为false
+
+	Backlinks []LinkRewriteResult //引用了该页面的页面及修改的引用数量
+	Failed    []LinkRewriteResult //内容无法解析或修改失败而跳过的页面，Error为失败原因
+}
+
+// 修改页面标题，并将其他页面中通过旧标题对该页面的引用改为新标题
+//
+// 通过CQL搜索时以旧标题的全文检索结果作为候选页面，再解析候选页面的内容确认引用，
+// 搜索索引未更新的页面和FullScan中说明的引用可能会被遗漏，需要完整修改时使用FullScan。
+// 内容无法解析或修改失败的页面会被跳过，记录在RenameReport.Failed中
+func (cli *Client) RenamePage(id, newTitle string, opt RenameOption) (RenameReport, error) {
+	if strings.TrimSpace(newTitle) == "" {
+		return RenameReport{}, fmt.Errorf("新标题不能为空")
+	}
+
+	content, err := cli.ContentByIdWithOpt(id, ExpandOpt(Expand.Body.Storage, Expand.Version, Expand.Space))
+	if err != nil {
+		return RenameReport{}, fmt.Errorf("获取页面%s失败: %s", id, err)
+	}
+
+	report := RenameReport{
+		PageId:   content.Id,
+		SpaceKey: content.Space.Key,
+		OldTitle: content.Title,
+		NewTitle: newTitle,
+	}
+	if content.Title == newTitle {
+		return report, nil
+	}
+
+	existing, err := cli.ContentBySpaceAndTitle(report.SpaceKey, newTitle)
+	if err != nil {
+		return report, fmt.Errorf("查找页面%s失败: %s", newTitle, err)
+	}
+	if existing.Id != "" {
+		return report, fmt.Errorf("空间%s中已存在标题为%s的页面", report.SpaceKey, newTitle)
+	}
+
+	candidates, err := cli.backlinkCandidates(content, opt)
+	if err != nil {
+		return report, err
+	}
+
+	changes := []TitleChange{{SpaceKey: report.SpaceKey, OldTitle: report.OldTitle, NewTitle: newTitle}}
+
+	//先统计引用，DryRun时直接返回。无法解析的页面跳过，不影响其他页面
+	var backlinks []Content
+	for _, page := range candidates {
+		result, err := cli.rewriteContentLinks(page, page.Space.Key, changes, true)
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed = append(report.Failed, result)
+			continue
+		}
+		if result.Changes > 0 {
+			report.Backlinks = append(report.Backlinks, result)
+			backlinks = append(backlinks, page)
+		}
+	}
+
+	if opt.DryRun {
+		return report, nil
+	}
+
+	//先改名再修改引用，改名失败时不修改其他页面
+	_, err = cli.ContentUpdateFunc(id, func(content *Content) error {
+		//页面中引用自身的链接一并修改
+		body, _, err := RewritePageLinks(content.Body.Storage.Value, report.SpaceKey, cli.Hostname, changes)
+		if err != nil {
+			return err
+		}
+
+		content.Title = newTitle
+		content.Body.Storage.Value = body
+		content.Version.Message = "页面改名: " + report.OldTitle
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("修改页面标题失败: %s", err)
+	}
+	report.Renamed = true
+
+	report.Backlinks = nil
+	for _, page := range backlinks {
+		result, err := cli.rewriteContentLinks(page, page.Space.Key, changes, false)
+		if err != nil {
+			result.Changes = 0
+			result.Error = err.Error()
+			report.Failed = append(report.Failed, result)
+			continue
+		}
+		report.Backlinks = append(report.Backlinks, result)
+	}
+
+	return report, nil
+}
+
+// 查找可能引用了页面的候选页面，不包括页面本身。返回的页面包含Storage内容和空间
+func (cli *Client) backlinkCandidates(content Content, opt RenameOption) ([]Content, error) {
+	if !opt.FullScan {
+		cql := fmt.Sprintf(`text ~ "%s" and type in (page, blogpost)`, cqlEscape(content.Title))
+		pages, err := cli.AllContentSearch(cql, ExpandOpt(Expand.Body.Storage, Expand.Space))
+		if err == nil {
+			return excludeContent(pages, content.Id), nil
+		}
+		if !IsNotSupported(err) {
+			return nil, fmt.Errorf("搜索引用页面失败: %s", err)
+		}
+	}
+
+	spaces := opt.Spaces
+	if len(spaces) == 0 {
+		spaces = []string{content.Space.Key}
+	}
+
+	var pages []Content
+	for _, space := range spaces {
+		contents, err := cli.allSpacePagesAndBlogs(space, ExpandOpt(Expand.Body.Storage))
+		if err != nil {
+			return nil, fmt.Errorf("获取空间%s的页面失败: %s", space, err)
+		}
+
+		for _, page := range contents {
+			if page.Space.Key == "" {
+				page.Space.Key = space
+			}
+			pages = append(pages, page)
+		}
+	}
+
+	return excludeContent(pages, content.Id), nil
+}
+
+// 去除指定ID的内容
+func excludeContent(contents []Content, id string) []Content {
+	var result []Content
+	for _, content := range contents {
+		if content.Id != id {
+			result = append(result, content)
+		}
+	}
+	return result
+}
+
+// 转义CQL字符串中的引号和反斜杠
+func cqlEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
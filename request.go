@@ -20,6 +20,28 @@ type ErrorResp struct {
 	Reason     string
 }
 
+// 带有HTTP状态码的错误，错误信息格式与其他接口一致
+type ResponseError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("[%d]%s", e.StatusCode, e.Message)
+}
+
+// 是否为版本冲突错误，即更新时页面已被他人修改
+func IsVersionConflict(err error) bool {
+	e, ok := err.(*ResponseError)
+	return ok && e.StatusCode == http.StatusConflict
+}
+
+// 是否为内容不存在的错误
+func IsNotFound(err error) bool {
+	e, ok := err.(*ResponseError)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
 // 错误响应中的错误数据
 type ErrorData struct {
 	Authorized            bool
@@ -92,11 +114,30 @@ func (cli *Client) ApiPUT(path string, data interface{}) (*http.Response, error)
 
 //发起POST类型的文件上传请求
 func (cli *Client) ApiPOSTFiles(path string, files []string) (*http.Response, error) {
+	return cli.ApiPOSTFilesWithFields(path, files, nil, nil)
+}
+
+// 以multipart格式上传文件，names指定各个文件上传后的名称（为空时使用文件路径），fields为附加的表单字段
+func (cli *Client) ApiPOSTFilesWithFields(path string, files, names []string, fields url.Values) (*http.Response, error) {
 	var body bytes.Buffer
 
 	w := multipart.NewWriter(&body)
-	for _, file := range files {
-		fw, err := w.CreateFormFile("file", file)
+	for key, values := range fields {
+		for _, value := range values {
+			err := w.WriteField(key, value)
+			if err != nil {
+				return nil, fmt.Errorf("创建表单字段错误: %s", err)
+			}
+		}
+	}
+
+	for i, file := range files {
+		name := file
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		fw, err := w.CreateFormFile("file", name)
 		if err != nil {
 			return nil, fmt.Errorf("创建上传字段错误: %s", err)
 		}
@@ -0,0 +1,292 @@
+package confluence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-http/confluence/storage"
+)
+
+// 版本冲突时重新获取页面并重试更新的次数
+var ConflictRetries = 3
+
+// 页面中的一个区段，用于只更新页面的一部分内容而保留其他内容
+type Section interface {
+	// 在页面内容中定位区段，返回区段所在的父节点，以及区段在父节点子节点中的范围[start, end)
+	Locate(doc *storage.Node) (parent *storage.Node, start, end int, err error)
+}
+
+// 两个锚点宏之间的区段，不包括锚点本身
+//
+// 锚点通常位于单独的段落中，此时区段为两个锚点段落之间的内容
+type AnchorSection struct {
+	Start string //起始锚点的名称
+	End   string //结束锚点的名称
+}
+
+func (s AnchorSection) Locate(doc *storage.Node) (*storage.Node, int, int, error) {
+	start := findAnchor(doc, s.Start)
+	if start == nil {
+		return nil, 0, 0, fmt.Errorf("未找到锚点%s", s.Start)
+	}
+
+	end := findAnchor(doc, s.End)
+	if end == nil {
+		return nil, 0, 0, fmt.Errorf("未找到锚点%s", s.End)
+	}
+
+	//找到两个锚点最近的公共祖先，区段为公共祖先下两个锚点所在子节点之间的内容
+	parent := start.Parent
+	for parent != nil && !isAncestor(parent, end) {
+		parent = parent.Parent
+	}
+	if parent == nil {
+		return nil, 0, 0, fmt.Errorf("锚点%s和%s不在同一文档中", s.Start, s.End)
+	}
+
+	i, j := childIndex(parent, start), childIndex(parent, end)
+	if i >= j {
+		return nil, 0, 0, fmt.Errorf("锚点%s不在%s之前", s.Start, s.End)
+	}
+
+	return parent, i + 1, j, nil
+}
+
+// 宏的富文本内容区段，如expand、panel、section等宏的内容
+type MacroSection struct {
+	Name   string            //宏的名称
+	Id     string            //宏的ac:macro-id，为空时不限制
+	Params map[string]string //宏参数需要满足的值，如{"title":"状态"}
+	Index  int               //满足条件的第几个宏，从0开始
+}
+
+func (s MacroSection) Locate(doc *storage.Node) (*storage.Node, int, int, error) {
+	var matched []storage.Macro
+	for _, macro := range doc.Macros(s.Name) {
+		if s.Id != "" && macro.MacroId() != s.Id {
+			continue
+		}
+
+		ok := true
+		for name, value := range s.Params {
+			if macro.Param(name) != value {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = append(matched, macro)
+		}
+	}
+
+	if s.Index < 0 || s.Index >= len(matched) {
+		return nil, 0, 0, fmt.Errorf("未找到第%d个满足条件的%s宏", s.Index+1, s.Name)
+	}
+
+	macro := matched[s.Index]
+	if macro.Child("ac:plain-text-body") != nil {
+		return nil, 0, 0, fmt.Errorf("%s宏的内容为纯文本，不能替换为Storage内容", s.Name)
+	}
+
+	body := macro.Body()
+	if body == nil {
+		macro.SetBody()
+		body = macro.Body()
+	}
+
+	return body, 0, len(body.Children), nil
+}
+
+// 标题下的区段：从标题之后到下一个同级或更高级标题之前的内容
+type HeadingSection struct {
+	Title string //标题的文本，忽略首尾空白
+	Level int    //标题的级别1-6，为0时不限制
+}
+
+func (s HeadingSection) Locate(doc *storage.Node) (*storage.Node, int, int, error) {
+	heading := doc.FindFirst(func(n *storage.Node) bool {
+		level := headingLevel(n)
+		return level > 0 && (s.Level == 0 || level == s.Level) &&
+			strings.TrimSpace(n.Text()) == strings.TrimSpace(s.Title)
+	})
+	if heading == nil {
+		return nil, 0, 0, fmt.Errorf("未找到标题%s", s.Title)
+	}
+
+	parent := heading.Parent
+	level := headingLevel(heading)
+	start := childIndex(parent, heading) + 1
+
+	end := start
+	for ; end < len(parent.Children); end++ {
+		if l := headingLevel(parent.Children[end]); l > 0 && l <= level {
+			break
+		}
+	}
+
+	return parent, start, end, nil
+}
+
+// 获取页面内容中区段的Storage内容
+func SectionContent(body string, section Section) (string, error) {
+	doc, err := storage.Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("解析页面内容失败: %s", err)
+	}
+
+	parent, start, end, err := section.Locate(doc)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, child := range parent.Children[start:end] {
+		sb.WriteString(child.String())
+	}
+	return sb.String(), nil
+}
+
+// 将页面内容中的区段替换为新的Storage内容，区段以外的内容保持不变
+func ReplaceSection(body string, section Section, content string) (string, error) {
+	doc, err := storage.Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("解析页面内容失败: %s", err)
+	}
+
+	nodes, err := storage.ParseFragment(content)
+	if err != nil {
+		return "", fmt.Errorf("解析区段内容失败: %s", err)
+	}
+
+	parent, start, end, err := section.Locate(doc)
+	if err != nil {
+		return "", err
+	}
+
+	children := append([]*storage.Node{}, parent.Children[:start]...)
+	children = append(children, nodes...)
+	children = append(children, parent.Children[end:]...)
+	parent.SetChildren(children...)
+
+	return doc.String(), nil
+}
+
+// 获取最新的页面内容并修改后更新，版本冲突时重新获取并重试
+//
+// update修改content的标题或内容，未修改时不更新页面，返回获取到的页面
+func (cli *Client) ContentUpdateFunc(id string, update func(content *Content) error) (Content, error) {
+	for i := 0; ; i++ {
+		content, err := cli.ContentByIdWithOpt(id, ExpandOpt(Expand.Body.Storage, Expand.Version, Expand.Space))
+		if err != nil {
+			return Content{}, err
+		}
+
+		//获取到的版本说明是上一次修改的，不能沿用到新版本
+		content.Version.Message = ""
+
+		title, body := content.Title, content.Body.Storage.Value
+		err = update(&content)
+		if err != nil {
+			return Content{}, err
+		}
+
+		if content.Title == title && content.Body.Storage.Value == body {
+			return content, nil
+		}
+
+		content.Version.Number += 1
+		if content.Version.Message == "" {
+			content.Version.Message = time.Now().Local().Format("机器人更新于2006-01-02 15:04:05")
+		}
+		content.SetStorageBody(content.Body.Storage.Value)
+
+		updated, err := cli.ContentUpdate(content)
+		if err != nil && IsVersionConflict(err) && i < ConflictRetries {
+			continue
+		}
+		return updated, err
+	}
+}
+
+// 只更新页面中的一个区段，区段以外的内容（包括其他人的修改）保持不变
+func (cli *Client) ContentSectionUpdate(id string, section Section, content string) (Content, error) {
+	return cli.ContentSectionUpdateFunc(id, section, func(string) (string, error) {
+		return content, nil
+	})
+}
+
+// 根据区段的当前内容计算新内容并更新，版本冲突时以最新内容重新计算
+func (cli *Client) ContentSectionUpdateFunc(id string, section Section, update func(old string) (string, error)) (Content, error) {
+	return cli.ContentUpdateFunc(id, func(content *Content) error {
+		old, err := SectionContent(content.Body.Storage.Value, section)
+		if err != nil {
+			return err
+		}
+
+		data, err := update(old)
+		if err != nil {
+			return err
+		}
+
+		if data == old {
+			return nil
+		}
+
+		body, err := ReplaceSection(content.Body.Storage.Value, section, data)
+		if err != nil {
+			return err
+		}
+
+		content.Body.Storage.Value = body
+		content.Version.Message = "更新区段内容"
+		return nil
+	})
+}
+
+// 查找指定名称的锚点宏
+func findAnchor(doc *storage.Node, name string) *storage.Node {
+	for _, macro := range doc.Macros("anchor") {
+		if macro.Param("") == name {
+			return macro.Node
+		}
+	}
+	return nil
+}
+
+// a是否为n的祖先
+func isAncestor(a, n *storage.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p == a {
+			return true
+		}
+	}
+	return false
+}
+
+// parent下包含n的直接子节点的位置
+func childIndex(parent, n *storage.Node) int {
+	for n.Parent != parent {
+		n = n.Parent
+	}
+	for i, child := range parent.Children {
+		if child == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// 标题元素的级别，不是标题时返回0
+func headingLevel(n *storage.Node) int {
+	if n.Type != storage.ElementNode || len(n.Name) != 2 || n.Name[0] != 'h' {
+		return 0
+	}
+
+	level, err := strconv.Atoi(n.Name[1:])
+	if err != nil || level < 1 || level > 6 {
+		return 0
+	}
+	return level
+}
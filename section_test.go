@@ -0,0 +1,178 @@
+package confluence
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const sectionTestBody = `<h1>Intro</h1><p>intro</p>` +
+	`<h2>Status</h2><p>old</p><h3>Detail</h3><p>detail</p>` +
+	`<h2>Next</h2><p>next</p>` +
+	`<p><ac:structured-macro ac:name="anchor"><ac:parameter ac:name="">begin</ac:parameter></ac:structured-macro></p>` +
+	`<p>between</p>` +
+	`<p><ac:structured-macro ac:name="anchor"><ac:parameter ac:name="">end</ac:parameter></ac:structured-macro></p>` +
+	`<ac:structured-macro ac:name="expand" ac:macro-id="m1"><ac:parameter ac:name="title">A</ac:parameter><ac:rich-text-body><p>a</p></ac:rich-text-body></ac:structured-macro>` +
+	`<ac:structured-macro ac:name="expand" ac:macro-id="m2"><ac:parameter ac:name="title">B</ac:parameter><ac:rich-text-body><p>b</p></ac:rich-text-body></ac:structured-macro>` +
+	`<ac:structured-macro ac:name="expand"><ac:parameter ac:name="title">C</ac:parameter></ac:structured-macro>` +
+	`<ac:structured-macro ac:name="code"><ac:plain-text-body><![CDATA[x]]></ac:plain-text-body></ac:structured-macro>`
+
+func TestSectionContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		section Section
+		want    string
+	}{
+		{"标题到下一个同级标题", HeadingSection{Title: "Status"}, `<p>old</p><h3>Detail</h3><p>detail</p>`},
+		{"标题到下一个更高级标题", HeadingSection{Title: "Detail", Level: 3}, `<p>detail</p>`},
+		{"没有更高级标题时到末尾", HeadingSection{Title: " Intro "}, `<p>intro</p><h2>Status</h2><p>old</p><h3>Detail</h3><p>detail</p><h2>Next</h2><p>next</p>` + sectionTestBody[strings.Index(sectionTestBody, `<p><ac:structured-macro ac:name="anchor">`):]},
+		{"锚点之间", AnchorSection{Start: "begin", End: "end"}, `<p>between</p>`},
+		{"宏参数", MacroSection{Name: "expand", Params: map[string]string{"title": "B"}}, `<p>b</p>`},
+		{"宏ID", MacroSection{Name: "expand", Id: "m1"}, `<p>a</p>`},
+		{"第几个宏", MacroSection{Name: "expand", Index: 1}, `<p>b</p>`},
+		{"没有内容的宏", MacroSection{Name: "expand", Index: 2}, ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SectionContent(sectionTestBody, tt.section)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("区段内容为\n%s\n期望\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceSection(t *testing.T) {
+	tests := []struct {
+		name    string
+		section Section
+		old     string
+		new     string
+	}{
+		{
+			name:    "标题",
+			section: HeadingSection{Title: "Status", Level: 2},
+			old:     `<h2>Status</h2><p>old</p><h3>Detail</h3><p>detail</p><h2>Next</h2>`,
+			new:     `<h2>Status</h2><p>new</p><h2>Next</h2>`,
+		},
+		{
+			name:    "锚点",
+			section: AnchorSection{Start: "begin", End: "end"},
+			old:     `</p><p>between</p><p>`,
+			new:     `</p><p>x</p><p>new</p><p>`,
+		},
+		{
+			name:    "宏",
+			section: MacroSection{Name: "expand", Id: "m2"},
+			old:     `<ac:rich-text-body><p>b</p></ac:rich-text-body>`,
+			new:     `<ac:rich-text-body><p>new</p></ac:rich-text-body>`,
+		},
+		{
+			name:    "没有内容的宏",
+			section: MacroSection{Name: "expand", Params: map[string]string{"title": "C"}},
+			old:     `<ac:parameter ac:name="title">C</ac:parameter></ac:structured-macro>`,
+			new:     `<ac:parameter ac:name="title">C</ac:parameter><ac:rich-text-body><p>new</p></ac:rich-text-body></ac:structured-macro>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := `<p>new</p>`
+			if _, ok := tt.section.(AnchorSection); ok {
+				content = `<p>x</p><p>new</p>`
+			}
+
+			got, err := ReplaceSection(sectionTestBody, tt.section, content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			//区段以外的内容保持不变
+			want := strings.Replace(sectionTestBody, tt.old, tt.new, 1)
+			if got != want {
+				t.Errorf("替换结果为\n%s\n期望\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestSectionLocateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		section Section
+	}{
+		{"标题不存在", HeadingSection{Title: "Missing"}},
+		{"标题级别不符", HeadingSection{Title: "Status", Level: 3}},
+		{"起始锚点不存在", AnchorSection{Start: "missing", End: "end"}},
+		{"锚点顺序颠倒", AnchorSection{Start: "end", End: "begin"}},
+		{"宏不存在", MacroSection{Name: "expand", Params: map[string]string{"title": "D"}}},
+		{"宏序号超出范围", MacroSection{Name: "expand", Index: 3}},
+		{"纯文本内容的宏", MacroSection{Name: "code"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := SectionContent(sectionTestBody, tt.section)
+			if err == nil {
+				t.Error("应返回错误")
+			}
+
+			_, err = ReplaceSection(sectionTestBody, tt.section, `<p>new</p>`)
+			if err == nil {
+				t.Error("替换时应返回错误")
+			}
+		})
+	}
+}
+
+// 更新页面时不沿用获取到的上一个版本的说明
+func TestContentUpdateFuncVersionMessage(t *testing.T) {
+	var messages []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			data, _ := ioutil.ReadAll(r.Body)
+			var content Content
+			json.Unmarshal(data, &content)
+			messages = append(messages, content.Version.Message)
+			w.Write(data)
+			return
+		}
+
+		w.Write([]byte(`{"id":"1","type":"page","title":"T","version":{"number":3,"message":"上次的说明"},` +
+			`"body":{"storage":{"value":"<h2>Status</h2><p>old</p>","representation":"storage"}}}`))
+	}))
+	defer ts.Close()
+
+	cli := New(ts.URL, "", "")
+	cli.ApiVersion = 1
+
+	_, err := cli.ContentUpdateFunc("1", func(content *Content) error {
+		content.Body.Storage.Value += "<p>more</p>"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cli.ContentSectionUpdate("1", HeadingSection{Title: "Status"}, "<p>new</p>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("更新了%d次", len(messages))
+	}
+	if !strings.HasPrefix(messages[0], "机器人更新于") {
+		t.Errorf("缺省的版本说明为%q", messages[0])
+	}
+	if messages[1] != "更新区段内容" {
+		t.Errorf("区段更新的版本说明为%q", messages[1])
+	}
+}
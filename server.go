@@ -0,0 +1,92 @@
+package confluence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Confluence服务器的信息
+type ServerInfo struct {
+	Cloud        bool         // 是否为Cloud版本
+	Version      string       // 版本号，如7.19.5
+	BuildNumber  string       // 构建号
+	Edition      string       // Cloud版本的版本类型
+	BaseUrl      string       // 服务器的访问地址
+	Capabilities Capabilities // 服务器支持的能力
+}
+
+// Confluence服务器支持的能力
+type Capabilities struct {
+	ContentMove bool // 移动内容: PUT /content/{id}/move/{position}/{targetId}
+	BodyConvert bool // 内容格式转换: POST /contentbody/convert/{to}
+	CQLSearch   bool // CQL搜索: GET /content/search
+	LongTask    bool // 长任务: GET /longtask/{id}
+	RestV2      bool // Cloud的REST v2接口
+	PageExport  bool // 单页面导出PDF/Word: /spaces/flyingpdf/pdfpageexport.action、/exportword
+	SpaceExport bool // 空间导出: /spaces/doexportspace.action、/spaces/flyingpdf/doflyingpdf.action
+}
+
+// 当前服务器不支持指定功能的错误
+type NotSupportedError struct {
+	Feature string
+	Server  ServerInfo
+}
+
+func (e *NotSupportedError) Error() string {
+	server := "Confluence Server " + e.Server.Version
+	if e.Server.Cloud {
+		server = "Confluence Cloud"
+	}
+	return fmt.Sprintf("%s不支持%s", server, e.Feature)
+}
+
+// 判断错误是否为不支持的功能错误
+func IsNotSupported(err error) bool {
+	_, ok := err.(*NotSupportedError)
+	return ok
+}
+
+// 版本号是否不低于指定的主、次版本号
+//
+// 版本号为空或无法解析时视为未知版本，返回true，不限制功能而交由实际请求返回错误
+func (info *ServerInfo) AtLeast(major, minor int) bool {
+	parts := strings.SplitN(info.Version, ".", 3)
+
+	m, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return true
+	}
+	n := 0
+	if len(parts) > 1 {
+		n, _ = strconv.Atoi(parts[1])
+	}
+
+	return m > major || (m == major && n >= minor)
+}
+
+// 根据版本信息计算服务器的能力
+func (info *ServerInfo) detectCapabilities() {
+	if info.Cloud {
+		//Cloud支持单页面导出PDF和Word，但没有doexportspace.action等空间导出页面，
+		//空间导出只能在管理界面中操作
+		info.Capabilities = Capabilities{
+			ContentMove: true,
+			BodyConvert: true,
+			CQLSearch:   true,
+			LongTask:    true,
+			RestV2:      true,
+			PageExport:  true,
+		}
+		return
+	}
+
+	info.Capabilities = Capabilities{
+		ContentMove: info.AtLeast(7, 11),
+		BodyConvert: info.AtLeast(5, 5),
+		CQLSearch:   info.AtLeast(5, 5),
+		LongTask:    info.AtLeast(5, 5),
+		PageExport:  true,
+		SpaceExport: info.AtLeast(5, 5),
+	}
+}
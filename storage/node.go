@@ -0,0 +1,390 @@
+// storage包用于解析、查询、修改和序列化Confluence的Storage格式（XHTML）内容
+//
+// 解析结果保留原文中的实体、CDATA、注释、空白和属性写法，未修改的部分序列化后与原文完全一致
+package storage
+
+import (
+	"html"
+	"strings"
+)
+
+// 节点类型
+type NodeType int
+
+const (
+	DocumentNode NodeType = iota //文档根节点
+	ElementNode                  //元素
+	TextNode                     //文本
+	CDATANode                    //CDATA段
+	CommentNode                  //注释
+	RawNode                      //处理指令、DOCTYPE等原样保留的内容
+)
+
+// 元素的属性
+type Attr struct {
+	Name  string //带前缀的属性名，如ac:name
+	Value string //实体解码后的属性值
+}
+
+// Storage格式的节点
+type Node struct {
+	Type        NodeType
+	Name        string //带前缀的元素名，如ac:structured-macro
+	Attrs       []Attr
+	Data        string //文本（实体解码后）、CDATA、注释或原样保留的内容
+	SelfClosing bool   //元素是否为自闭合形式
+
+	Parent   *Node
+	Children []*Node
+
+	Line   int //节点在原文中的行号，从1开始，新建的节点为0
+	Column int //节点在原文中的列号，从1开始
+
+	raw    string //原文中的开始标签或文本，节点被修改后清空
+	rawEnd string //原文中的结束标签
+}
+
+// 创建元素，attrs为属性名和属性值交替的列表
+func NewElement(name string, attrs ...string) *Node {
+	node := &Node{Type: ElementNode, Name: name}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		node.Attrs = append(node.Attrs, Attr{Name: attrs[i], Value: attrs[i+1]})
+	}
+	return node
+}
+
+// 创建文本节点
+func NewText(text string) *Node {
+	return &Node{Type: TextNode, Data: text}
+}
+
+// 创建CDATA节点
+func NewCDATA(data string) *Node {
+	return &Node{Type: CDATANode, Data: data}
+}
+
+// 元素名的前缀，如ac:link的前缀为ac
+func (n *Node) Prefix() string {
+	if k := strings.IndexByte(n.Name, ':'); k >= 0 {
+		return n.Name[:k]
+	}
+	return ""
+}
+
+// 元素名去掉前缀后的部分
+func (n *Node) Local() string {
+	if k := strings.IndexByte(n.Name, ':'); k >= 0 {
+		return n.Name[k+1:]
+	}
+	return n.Name
+}
+
+// 是否为指定名称的元素
+func (n *Node) Is(name string) bool {
+	return n.Type == ElementNode && n.Name == name
+}
+
+// 获取属性值，属性不存在时返回空字符串
+func (n *Node) Attr(name string) string {
+	value, _ := n.LookupAttr(name)
+	return value
+}
+
+// 获取属性值及属性是否存在
+func (n *Node) LookupAttr(name string) (string, bool) {
+	for _, attr := range n.Attrs {
+		if attr.Name == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// 设置属性值，属性不存在时添加到末尾
+func (n *Node) SetAttr(name, value string) {
+	n.raw = ""
+	for i, attr := range n.Attrs {
+		if attr.Name == name {
+			n.Attrs[i].Value = value
+			return
+		}
+	}
+	n.Attrs = append(n.Attrs, Attr{Name: name, Value: value})
+}
+
+// 删除属性
+func (n *Node) RemoveAttr(name string) {
+	for i, attr := range n.Attrs {
+		if attr.Name == name {
+			n.raw = ""
+			n.Attrs = append(n.Attrs[:i], n.Attrs[i+1:]...)
+			return
+		}
+	}
+}
+
+// 设置文本或CDATA节点的内容
+func (n *Node) SetData(data string) {
+	n.raw = ""
+	n.Data = data
+}
+
+// 节点及其子孙中所有文本和CDATA的内容
+func (n *Node) Text() string {
+	switch n.Type {
+	case TextNode, CDATANode:
+		return n.Data
+	case ElementNode, DocumentNode:
+		var sb strings.Builder
+		for _, child := range n.Children {
+			sb.WriteString(child.Text())
+		}
+		return sb.String()
+	}
+	return ""
+}
+
+// 第一个子元素
+func (n *Node) FirstElement() *Node {
+	for _, child := range n.Children {
+		if child.Type == ElementNode {
+			return child
+		}
+	}
+	return nil
+}
+
+// 第一个指定名称的子元素
+func (n *Node) Child(name string) *Node {
+	for _, child := range n.Children {
+		if child.Is(name) {
+			return child
+		}
+	}
+	return nil
+}
+
+// 所有指定名称的子元素
+func (n *Node) ChildElements(name string) []*Node {
+	var nodes []*Node
+	for _, child := range n.Children {
+		if child.Is(name) {
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes
+}
+
+// 在末尾添加子节点，子节点原来有父节点时先从原位置移除
+func (n *Node) AppendChild(children ...*Node) {
+	for _, child := range children {
+		child.Remove()
+		child.Parent = n
+		n.Children = append(n.Children, child)
+	}
+	n.openTag()
+}
+
+// 在子节点ref之前插入节点，ref为空时添加到末尾
+func (n *Node) InsertBefore(ref *Node, children ...*Node) {
+	if ref == nil {
+		n.AppendChild(children...)
+		return
+	}
+
+	for _, child := range children {
+		child.Remove()
+		child.Parent = n
+	}
+
+	i := n.indexOf(ref)
+	if i < 0 {
+		n.AppendChild(children...)
+		return
+	}
+
+	rest := append(append([]*Node{}, children...), n.Children[i:]...)
+	n.Children = append(n.Children[:i], rest...)
+	n.openTag()
+}
+
+// 自闭合的元素添加子节点后改为开始标签和结束标签的形式
+func (n *Node) openTag() {
+	if n.SelfClosing {
+		n.SelfClosing = false
+		n.raw = ""
+	}
+}
+
+// 从父节点中移除
+func (n *Node) Remove() {
+	if n.Parent == nil {
+		return
+	}
+
+	parent := n.Parent
+	if i := parent.indexOf(n); i >= 0 {
+		parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+	}
+	n.Parent = nil
+}
+
+// 用指定的节点替换当前节点
+func (n *Node) ReplaceWith(nodes ...*Node) {
+	parent := n.Parent
+	if parent == nil {
+		return
+	}
+
+	next := n.NextSibling()
+	n.Remove()
+	parent.InsertBefore(next, nodes...)
+}
+
+// 清空子节点后设置新的子节点
+func (n *Node) SetChildren(children ...*Node) {
+	for _, child := range n.Children {
+		child.Parent = nil
+	}
+	n.Children = nil
+	n.AppendChild(children...)
+}
+
+// 后一个兄弟节点
+func (n *Node) NextSibling() *Node {
+	if n.Parent == nil {
+		return nil
+	}
+
+	i := n.Parent.indexOf(n)
+	if i < 0 || i+1 >= len(n.Parent.Children) {
+		return nil
+	}
+	return n.Parent.Children[i+1]
+}
+
+// 前一个兄弟节点
+func (n *Node) PrevSibling() *Node {
+	if n.Parent == nil {
+		return nil
+	}
+
+	i := n.Parent.indexOf(n)
+	if i <= 0 {
+		return nil
+	}
+	return n.Parent.Children[i-1]
+}
+
+// 子节点的位置
+func (n *Node) indexOf(child *Node) int {
+	for i, c := range n.Children {
+		if c == child {
+			return i
+		}
+	}
+	return -1
+}
+
+// 深拷贝节点，拷贝结果没有父节点
+func (n *Node) Clone() *Node {
+	clone := *n
+	clone.Parent = nil
+	clone.Attrs = append([]Attr(nil), n.Attrs...)
+	clone.Children = nil
+	for _, child := range n.Children {
+		c := child.Clone()
+		c.Parent = &clone
+		clone.Children = append(clone.Children, c)
+	}
+	return &clone
+}
+
+// 序列化节点，未修改的部分与原文一致
+func (n *Node) String() string {
+	var sb strings.Builder
+	n.render(&sb)
+	return sb.String()
+}
+
+// 序列化所有子节点
+func (n *Node) InnerXML() string {
+	var sb strings.Builder
+	for _, child := range n.Children {
+		child.render(&sb)
+	}
+	return sb.String()
+}
+
+// 序列化节点
+func (n *Node) render(sb *strings.Builder) {
+	switch n.Type {
+	case DocumentNode:
+		for _, child := range n.Children {
+			child.render(sb)
+		}
+	case TextNode:
+		if n.raw != "" {
+			sb.WriteString(n.raw)
+		} else {
+			sb.WriteString(EscapeText(n.Data))
+		}
+	case CDATANode:
+		sb.WriteString("<![CDATA[" + strings.Replace(n.Data, "]]>", "]]]]><![CDATA[>", -1) + "]]>")
+	case CommentNode:
+		sb.WriteString("<!--" + n.Data + "-->")
+	case RawNode:
+		sb.WriteString(n.Data)
+	case ElementNode:
+		if n.raw != "" {
+			sb.WriteString(n.raw)
+		} else {
+			sb.WriteString("<" + n.Name)
+			for _, attr := range n.Attrs {
+				sb.WriteString(" " + attr.Name + `="` + EscapeAttr(attr.Value) + `"`)
+			}
+			if n.SelfClosing && len(n.Children) == 0 {
+				sb.WriteString("/>")
+				return
+			}
+			sb.WriteString(">")
+		}
+
+		if n.SelfClosing && len(n.Children) == 0 {
+			return
+		}
+
+		for _, child := range n.Children {
+			child.render(sb)
+		}
+
+		if n.rawEnd != "" {
+			sb.WriteString(n.rawEnd)
+		} else {
+			sb.WriteString("</" + n.Name + ">")
+		}
+	}
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+// 转义文本内容
+func EscapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// 转义属性值
+func EscapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
+
+// 解码文本或属性值中的实体
+func unescape(s string) string {
+	if strings.IndexByte(s, '&') < 0 {
+		return s
+	}
+	return html.UnescapeString(s)
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"testing"
+)
+
+// 解析内容，失败时终止测试
+func mustParse(t *testing.T, s string) *Node {
+	t.Helper()
+	doc, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestNodeMutations(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		mutate func(doc *Node)
+		want   string
+	}{
+		{
+			name:  "ReplaceWith",
+			input: `<p>a</p><p id='x'>b &amp; c</p><p>d</p>`,
+			mutate: func(doc *Node) {
+				nodes, _ := ParseFragment(`<h2>new</h2><hr/>`)
+				doc.ChildElements("p")[1].ReplaceWith(nodes...)
+			},
+			want: `<p>a</p><h2>new</h2><hr/><p>d</p>`,
+		},
+		{
+			name:  "ReplaceWith最后一个节点",
+			input: `<p>a</p><p>b</p>`,
+			mutate: func(doc *Node) {
+				doc.ChildElements("p")[1].ReplaceWith(NewText("x"))
+			},
+			want: `<p>a</p>x`,
+		},
+		{
+			name:  "Remove",
+			input: "<ul>\n<li>one</li>\n<li>two</li>\n</ul>",
+			mutate: func(doc *Node) {
+				doc.Child("ul").ChildElements("li")[0].Remove()
+			},
+			want: "<ul>\n\n<li>two</li>\n</ul>",
+		},
+		{
+			name:  "SetChildren",
+			input: `<td class="c"><p>old</p></td>`,
+			mutate: func(doc *Node) {
+				doc.Child("td").SetChildren(NewText("a < b"), NewElement("br"))
+			},
+			want: `<td class="c">a &lt; b<br></br></td>`,
+		},
+		{
+			name:  "SetChildren自闭合元素",
+			input: `<p>x</p><td/>`,
+			mutate: func(doc *Node) {
+				doc.Child("td").SetChildren(NewCDATA("]]>"))
+			},
+			want: `<p>x</p><td><![CDATA[]]]]><![CDATA[>]]></td>`,
+		},
+		{
+			name:  "SetAttr只重新生成修改的开始标签",
+			input: `<p  a='1'>x &nbsp;</p><p b = "2">y</p>`,
+			mutate: func(doc *Node) {
+				doc.ChildElements("p")[1].SetAttr("c", `"3"`)
+			},
+			want: `<p  a='1'>x &nbsp;</p><p b="2" c="&quot;3&quot;">y</p>`,
+		},
+		{
+			name:  "AppendChild移动节点",
+			input: `<p>a<b>b</b></p><p>c</p>`,
+			mutate: func(doc *Node) {
+				ps := doc.ChildElements("p")
+				ps[1].AppendChild(ps[0].Child("b"))
+			},
+			want: `<p>a</p><p>c<b>b</b></p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustParse(t, tt.input)
+			tt.mutate(doc)
+
+			if got := doc.String(); got != tt.want {
+				t.Errorf("修改结果为\n%s\n期望\n%s", got, tt.want)
+			}
+
+			//修改后的内容仍能解析
+			if _, err := Parse(doc.String()); err != nil {
+				t.Errorf("修改结果无法解析: %s", err)
+			}
+		})
+	}
+}
+
+func TestNodeRemoveDetaches(t *testing.T) {
+	doc := mustParse(t, `<p>a</p><p>b</p>`)
+	first := doc.ChildElements("p")[0]
+	first.Remove()
+
+	if first.Parent != nil {
+		t.Error("移除后的节点仍有父节点")
+	}
+	if len(doc.Children) != 1 || doc.Children[0].Text() != "b" {
+		t.Errorf("移除后的内容为%s", doc.String())
+	}
+
+	//已移除的节点再次移除和替换时不做修改
+	first.Remove()
+	first.ReplaceWith(NewText("x"))
+	if doc.String() != `<p>b</p>` {
+		t.Errorf("内容被修改为%s", doc.String())
+	}
+}
+
+func TestNodeClone(t *testing.T) {
+	doc := mustParse(t, `<p class="a">x<b>y</b></p>`)
+	p := doc.Child("p")
+	clone := p.Clone()
+
+	clone.SetAttr("class", "b")
+	clone.Child("b").SetChildren(NewText("z"))
+
+	if doc.String() != `<p class="a">x<b>y</b></p>` {
+		t.Errorf("修改拷贝影响了原节点: %s", doc.String())
+	}
+	if clone.String() != `<p class="b">x<b>z</b></p>` {
+		t.Errorf("拷贝为%s", clone.String())
+	}
+}
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 解析错误，包含出错位置的行号和列号
+type SyntaxError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("第%d行第%d列: %s", e.Line, e.Column, e.Msg)
+}
+
+// 解析Storage格式的内容，返回文档根节点
+//
+// Storage格式的内容是没有根元素的XHTML片段，ac:、ri:等前缀不需要声明，
+// 实体按HTML实体解码，元素必须正确闭合，否则返回包含位置信息的SyntaxError
+func Parse(storage string) (*Node, error) {
+	p := &parser{src: storage}
+	p.lineStarts = []int{0}
+	for i := 0; i < len(storage); i++ {
+		if storage[i] == '\n' {
+			p.lineStarts = append(p.lineStarts, i+1)
+		}
+	}
+
+	doc := &Node{Type: DocumentNode, Line: 1, Column: 1}
+	err := p.parse(doc)
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// 解析Storage格式的片段，返回顶层节点列表，用于构造要插入的内容
+func ParseFragment(storage string) ([]*Node, error) {
+	doc, err := Parse(storage)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := append([]*Node(nil), doc.Children...)
+	for _, node := range nodes {
+		node.Parent = nil
+	}
+	return nodes, nil
+}
+
+// 解析器
+type parser struct {
+	src        string
+	pos        int
+	lineStarts []int
+}
+
+// 指定偏移量的行号和列号
+func (p *parser) position(offset int) (int, int) {
+	lo, hi := 0, len(p.lineStarts)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if p.lineStarts[mid] <= offset {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	//列号按字符计算
+	return lo + 1, len([]rune(p.src[p.lineStarts[lo]:offset])) + 1
+}
+
+// 指定偏移量处的解析错误
+func (p *parser) errorAt(offset int, format string, args ...interface{}) error {
+	line, col := p.position(offset)
+	return &SyntaxError{Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// 创建指定偏移量处的节点
+func (p *parser) newNode(typ NodeType, offset int) *Node {
+	line, col := p.position(offset)
+	return &Node{Type: typ, Line: line, Column: col}
+}
+
+// 解析全部内容
+func (p *parser) parse(doc *Node) error {
+	stack := []*Node{doc}
+	current := func() *Node { return stack[len(stack)-1] }
+	appendNode := func(node *Node) {
+		parent := current()
+		node.Parent = parent
+		parent.Children = append(parent.Children, node)
+	}
+
+	for p.pos < len(p.src) {
+		start := p.pos
+		rest := p.src[p.pos:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return p.errorAt(start, "注释没有结束")
+			}
+			node := p.newNode(CommentNode, start)
+			node.Data = rest[4 : 4+end]
+			appendNode(node)
+			p.pos += 4 + end + 3
+
+		case strings.HasPrefix(rest, "<![CDATA["):
+			end := strings.Index(rest, "]]>")
+			if end < 0 {
+				return p.errorAt(start, "CDATA没有结束")
+			}
+			node := p.newNode(CDATANode, start)
+			node.Data = rest[9:end]
+			appendNode(node)
+			p.pos += end + 3
+
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return p.errorAt(start, "标记没有结束")
+			}
+			node := p.newNode(RawNode, start)
+			node.Data = rest[:end+1]
+			appendNode(node)
+			p.pos += end + 1
+
+		case strings.HasPrefix(rest, "</"):
+			name, n := scanName(rest[2:])
+			if name == "" {
+				return p.errorAt(start, "结束标签缺少元素名")
+			}
+			k := 2 + n
+			for k < len(rest) && isSpace(rest[k]) {
+				k++
+			}
+			if k >= len(rest) || rest[k] != '>' {
+				return p.errorAt(start, "结束标签</%s没有结束", name)
+			}
+
+			open := current()
+			if open.Type != ElementNode {
+				return p.errorAt(start, "多余的结束标签</%s>", name)
+			}
+			if open.Name != name {
+				return p.errorAt(start, "结束标签</%s>与第%d行第%d列的<%s>不匹配", name, open.Line, open.Column, open.Name)
+			}
+			open.rawEnd = rest[:k+1]
+			stack = stack[:len(stack)-1]
+			p.pos += k + 1
+
+		case strings.HasPrefix(rest, "<"):
+			node, err := p.parseStartTag()
+			if err != nil {
+				return err
+			}
+			appendNode(node)
+			if !node.SelfClosing {
+				stack = append(stack, node)
+			}
+
+		default:
+			end := strings.IndexByte(rest, '<')
+			if end < 0 {
+				end = len(rest)
+			}
+			node := p.newNode(TextNode, start)
+			node.raw = rest[:end]
+			node.Data = unescape(node.raw)
+			appendNode(node)
+			p.pos += end
+		}
+	}
+
+	if len(stack) > 1 {
+		open := current()
+		return p.errorAt(len(p.src), "元素<%s>（第%d行第%d列）没有结束", open.Name, open.Line, open.Column)
+	}
+
+	return nil
+}
+
+// 解析开始标签
+func (p *parser) parseStartTag() (*Node, error) {
+	start := p.pos
+	rest := p.src[p.pos:]
+
+	name, n := scanName(rest[1:])
+	if name == "" {
+		return nil, p.errorAt(start, "'<'后缺少元素名，文本中的'<'需要转义为&lt;")
+	}
+
+	node := p.newNode(ElementNode, start)
+	node.Name = name
+
+	k := 1 + n
+	for {
+		spaces := k
+		for k < len(rest) && isSpace(rest[k]) {
+			k++
+		}
+		if k >= len(rest) {
+			return nil, p.errorAt(start, "开始标签<%s没有结束", name)
+		}
+
+		if rest[k] == '>' {
+			k++
+			break
+		}
+		if strings.HasPrefix(rest[k:], "/>") {
+			node.SelfClosing = true
+			k += 2
+			break
+		}
+
+		if k == spaces {
+			return nil, p.errorAt(start+k, "属性之前缺少空白")
+		}
+
+		attrName, m := scanName(rest[k:])
+		if attrName == "" {
+			return nil, p.errorAt(start+k, "元素<%s>中有无效的字符%q", name, rest[k])
+		}
+		attrStart := k
+		k += m
+
+		for k < len(rest) && isSpace(rest[k]) {
+			k++
+		}
+		if k >= len(rest) || rest[k] != '=' {
+			return nil, p.errorAt(start+attrStart, "属性%s缺少值", attrName)
+		}
+		k++
+		for k < len(rest) && isSpace(rest[k]) {
+			k++
+		}
+		if k >= len(rest) || (rest[k] != '"' && rest[k] != '\'') {
+			return nil, p.errorAt(start+attrStart, "属性%s的值缺少引号", attrName)
+		}
+
+		quote := rest[k]
+		end := strings.IndexByte(rest[k+1:], quote)
+		if end < 0 {
+			return nil, p.errorAt(start+attrStart, "属性%s的值没有结束", attrName)
+		}
+		value := rest[k+1 : k+1+end]
+		if strings.IndexByte(value, '<') >= 0 {
+			return nil, p.errorAt(start+attrStart, "属性%s的值中的'<'需要转义", attrName)
+		}
+
+		if _, found := node.LookupAttr(attrName); found {
+			return nil, p.errorAt(start+attrStart, "重复的属性%s", attrName)
+		}
+		node.Attrs = append(node.Attrs, Attr{Name: attrName, Value: unescape(value)})
+		k += end + 2
+	}
+
+	node.raw = rest[:k]
+	p.pos += k
+
+	return node, nil
+}
+
+// 读取元素名或属性名，返回名称和长度
+func scanName(s string) (string, int) {
+	n := 0
+	for n < len(s) {
+		c := s[n]
+		if c >= 0x80 || c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(n > 0 && (c == '-' || c == '.' || (c >= '0' && c <= '9'))) {
+			n++
+			continue
+		}
+		break
+	}
+	return s[:n], n
+}
+
+// 是否为空白字符
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"testing"
+)
+
+// 解析后未修改的内容序列化后与原文一致
+func TestParseRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"空内容", ""},
+		{"纯文本", "plain text"},
+		{"实体", `<p>a &amp; b &lt;c&gt; &nbsp;&#169;&#xA9;&quot;</p>`},
+		{"CDATA", `<ac:plain-text-body><![CDATA[if a < b && c > d {}]]></ac:plain-text-body>`},
+		{"拆分的CDATA", `<ac:plain-text-body><![CDATA[a]]]]><![CDATA[>b]]></ac:plain-text-body>`},
+		{"注释", `<p>a<!-- <b>not an element</b> -->b</p>`},
+		{"处理指令", `<?xml version="1.0" encoding="UTF-8"?><!DOCTYPE html><p>x</p>`},
+		{"单引号属性", `<a href='http://example.com/?a=1&amp;b="2"'>x</a>`},
+		{"属性周围的空白", "<p  class = \"x\"\n\tid='y' >z</p >"},
+		{"自闭合", `<p>a<br/>b<br />c</p><ri:page ri:content-title="T" />`},
+		{"空白和换行", "<ul>\n  <li>one</li>\n\n  <li>two</li>\n</ul>\n"},
+		{"中文", `<h1>标题</h1><p>内容&amp;说明</p>`},
+		{"宏", `<ac:structured-macro ac:name="info" ac:schema-version="1" ac:macro-id="abc"><ac:parameter ac:name="title">T</ac:parameter><ac:rich-text-body><p>body</p></ac:rich-text-body></ac:structured-macro>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := doc.String(); got != tt.input {
+				t.Errorf("序列化结果为\n%s\n期望\n%s", got, tt.input)
+			}
+		})
+	}
+}
+
+// 解析时解码实体，文本和CDATA分别保存
+func TestParseDecoding(t *testing.T) {
+	doc, err := Parse(`<p title="a &amp; &quot;b&quot;">x &lt; y&nbsp;</p><ac:plain-text-body><![CDATA[<raw>]]></ac:plain-text-body>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := doc.Child("p")
+	if got := p.Attr("title"); got != `a & "b"` {
+		t.Errorf("属性值为%q", got)
+	}
+	if got := p.Text(); got != "x < y " {
+		t.Errorf("文本为%q", got)
+	}
+
+	body := doc.Child("ac:plain-text-body")
+	if len(body.Children) != 1 || body.Children[0].Type != CDATANode || body.Children[0].Data != "<raw>" {
+		t.Errorf("CDATA解析错误: %#v", body.Children)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		line   int
+		column int
+	}{
+		{"<p>a", 1, 5},
+		{"<p>\n<b>x</p>", 2, 5},
+		{"<p>a < b</p>", 1, 6},
+		{`<p class=x>a</p>`, 1, 4},
+		{`<p a="1" a="2">a</p>`, 1, 10},
+		{"</p>", 1, 1},
+		{"<!-- x", 1, 1},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		syntaxErr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: 错误为%v，期望SyntaxError", tt.input, err)
+			continue
+		}
+		if syntaxErr.Line != tt.line || syntaxErr.Column != tt.column {
+			t.Errorf("%q: 错误位置为%d:%d，期望%d:%d（%s）", tt.input, syntaxErr.Line, syntaxErr.Column, tt.line, tt.column, syntaxErr.Msg)
+		}
+	}
+}
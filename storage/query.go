@@ -0,0 +1,56 @@
+package storage
+
+// 深度优先遍历节点及其子孙，fn返回false时不再遍历该节点的子孙
+func (n *Node) Walk(fn func(*Node) bool) {
+	if !fn(n) {
+		return
+	}
+
+	//遍历时fn可能修改子节点列表，因此遍历副本
+	children := append([]*Node(nil), n.Children...)
+	for _, child := range children {
+		child.Walk(fn)
+	}
+}
+
+// 查找满足条件的所有子孙节点（不包括节点自身）
+func (n *Node) Find(match func(*Node) bool) []*Node {
+	var nodes []*Node
+	for _, child := range n.Children {
+		child.Walk(func(node *Node) bool {
+			if match(node) {
+				nodes = append(nodes, node)
+			}
+			return true
+		})
+	}
+	return nodes
+}
+
+// 查找第一个满足条件的子孙节点
+func (n *Node) FindFirst(match func(*Node) bool) *Node {
+	for _, child := range n.Children {
+		if match(child) {
+			return child
+		}
+		if found := child.FindFirst(match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// 查找指定名称的所有子孙元素
+func (n *Node) Elements(name string) []*Node {
+	return n.Find(func(node *Node) bool { return node.Is(name) })
+}
+
+// 最近的指定名称的祖先元素
+func (n *Node) Ancestor(name string) *Node {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Is(name) {
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package storage
+
+// Storage格式中的宏，包括ac:structured-macro和旧版本的ac:macro
+type Macro struct {
+	*Node
+}
+
+// 查找指定名称的宏，名称为空时返回所有宏
+func (n *Node) Macros(name string) []Macro {
+	var macros []Macro
+	for _, node := range n.Find(isMacro) {
+		macro := Macro{node}
+		if name == "" || macro.MacroName() == name {
+			macros = append(macros, macro)
+		}
+	}
+	return macros
+}
+
+// 查找指定macro-id的宏
+func (n *Node) MacroById(id string) (Macro, bool) {
+	node := n.FindFirst(func(node *Node) bool {
+		return isMacro(node) && node.Attr("ac:macro-id") == id
+	})
+	return Macro{node}, node != nil
+}
+
+// 是否为宏元素
+func isMacro(node *Node) bool {
+	return node.Is("ac:structured-macro") || node.Is("ac:macro")
+}
+
+// 宏的名称，如code、info
+func (m Macro) MacroName() string {
+	return m.Attr("ac:name")
+}
+
+// 宏的ID
+func (m Macro) MacroId() string {
+	return m.Attr("ac:macro-id")
+}
+
+// 宏参数的元素
+func (m Macro) paramNode(name string) *Node {
+	for _, child := range m.ChildElements("ac:parameter") {
+		if child.Attr("ac:name") == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// 宏参数的值
+func (m Macro) Param(name string) string {
+	if node := m.paramNode(name); node != nil {
+		return node.Text()
+	}
+	return ""
+}
+
+// 所有宏参数
+func (m Macro) Params() map[string]string {
+	params := make(map[string]string)
+	for _, child := range m.ChildElements("ac:parameter") {
+		params[child.Attr("ac:name")] = child.Text()
+	}
+	return params
+}
+
+// 设置宏参数，参数不存在时添加在其他参数之后
+func (m Macro) SetParam(name, value string) {
+	if node := m.paramNode(name); node != nil {
+		node.SetChildren(NewText(value))
+		return
+	}
+
+	param := NewElement("ac:parameter", "ac:name", name)
+	param.AppendChild(NewText(value))
+
+	//参数位于内容体之前
+	var ref *Node
+	for _, child := range m.Children {
+		if child.Type == ElementNode && child.Name != "ac:parameter" {
+			ref = child
+			break
+		}
+	}
+	m.InsertBefore(ref, param)
+}
+
+// 删除宏参数
+func (m Macro) RemoveParam(name string) {
+	if node := m.paramNode(name); node != nil {
+		node.Remove()
+	}
+}
+
+// 宏的富文本内容体ac:rich-text-body，不存在时返回nil
+func (m Macro) Body() *Node {
+	return m.Child("ac:rich-text-body")
+}
+
+// 设置宏的富文本内容
+func (m Macro) SetBody(children ...*Node) {
+	body := m.Body()
+	if body == nil {
+		body = NewElement("ac:rich-text-body")
+		m.AppendChild(body)
+	}
+	body.SetChildren(children...)
+}
+
+// 宏的纯文本内容体ac:plain-text-body的内容
+func (m Macro) PlainTextBody() string {
+	if body := m.Child("ac:plain-text-body"); body != nil {
+		return body.Text()
+	}
+	return ""
+}
+
+// 设置宏的纯文本内容，内容以CDATA的形式保存
+func (m Macro) SetPlainTextBody(text string) {
+	body := m.Child("ac:plain-text-body")
+	if body == nil {
+		body = NewElement("ac:plain-text-body")
+		m.AppendChild(body)
+	}
+	body.SetChildren(NewCDATA(text))
+}
+
+// Storage格式中的链接，包括ac:link和a元素
+type Link struct {
+	*Node
+}
+
+// 查找所有链接
+func (n *Node) Links() []Link {
+	var links []Link
+	for _, node := range n.Find(func(node *Node) bool { return node.Is("ac:link") || node.Is("a") }) {
+		links = append(links, Link{node})
+	}
+	return links
+}
+
+// 链接指向的资源元素，如ri:page、ri:attachment，a元素和页面内锚点链接返回nil
+func (l Link) Resource() *Node {
+	if !l.Is("ac:link") {
+		return nil
+	}
+	for _, child := range l.Children {
+		if child.Type == ElementNode && child.Prefix() == "ri" {
+			return child
+		}
+	}
+	return nil
+}
+
+// 链接的类型：page、blogpost、attachment、user、space等资源类型，a元素为url，页面内锚点为anchor
+func (l Link) Kind() string {
+	if l.Is("a") {
+		return "url"
+	}
+	if res := l.Resource(); res != nil {
+		return res.Local()
+	}
+	return "anchor"
+}
+
+// 链接的页面或博客标题
+func (l Link) PageTitle() string {
+	if res := l.Resource(); res != nil {
+		return res.Attr("ri:content-title")
+	}
+	return ""
+}
+
+// 设置链接的页面或博客标题
+func (l Link) SetPageTitle(title string) {
+	if res := l.Resource(); res != nil {
+		res.SetAttr("ri:content-title", title)
+	}
+}
+
+// 链接的空间，没有指定时为空表示当前空间
+func (l Link) SpaceKey() string {
+	if res := l.Resource(); res != nil {
+		return res.Attr("ri:space-key")
+	}
+	return ""
+}
+
+// 链接的附件文件名
+func (l Link) Filename() string {
+	if res := l.Resource(); res != nil && res.Local() == "attachment" {
+		return res.Attr("ri:filename")
+	}
+	return ""
+}
+
+// 附件所在页面的标题，为空时附件位于当前页面
+func (l Link) AttachmentPageTitle() string {
+	if res := l.Resource(); res != nil && res.Local() == "attachment" {
+		if page := res.Child("ri:page"); page != nil {
+			return page.Attr("ri:content-title")
+		}
+	}
+	return ""
+}
+
+// 链接的锚点
+func (l Link) Anchor() string {
+	return l.Attr("ac:anchor")
+}
+
+// a元素的链接地址
+func (l Link) Href() string {
+	return l.Attr("href")
+}
+
+// 链接显示的文本
+func (l Link) LinkText() string {
+	if l.Is("a") {
+		return l.Text()
+	}
+	for _, child := range l.Children {
+		if child.Is("ac:plain-text-link-body") || child.Is("ac:link-body") {
+			return child.Text()
+		}
+	}
+	return ""
+}
+
+// Storage格式中的图片ac:image
+type Image struct {
+	*Node
+}
+
+// 查找所有图片
+func (n *Node) Images() []Image {
+	var images []Image
+	for _, node := range n.Elements("ac:image") {
+		images = append(images, Image{node})
+	}
+	return images
+}
+
+// 图片的附件文件名，外部图片返回空字符串
+func (img Image) Filename() string {
+	if res := img.Child("ri:attachment"); res != nil {
+		return res.Attr("ri:filename")
+	}
+	return ""
+}
+
+// 外部图片的地址
+func (img Image) URL() string {
+	if res := img.Child("ri:url"); res != nil {
+		return res.Attr("ri:value")
+	}
+	return ""
+}
+
+// Storage格式中的表格
+type Table struct {
+	*Node
+}
+
+// 查找所有表格
+func (n *Node) Tables() []Table {
+	var tables []Table
+	for _, node := range n.Elements("table") {
+		tables = append(tables, Table{node})
+	}
+	return tables
+}
+
+// 表格的所有行，包括thead、tbody、tfoot中的行，不包括嵌套表格中的行
+func (t Table) Rows() []*Node {
+	var rows []*Node
+	for _, child := range t.Children {
+		switch {
+		case child.Is("tr"):
+			rows = append(rows, child)
+		case child.Is("thead"), child.Is("tbody"), child.Is("tfoot"):
+			rows = append(rows, child.ChildElements("tr")...)
+		}
+	}
+	return rows
+}
+
+// 行中的所有单元格
+func Cells(row *Node) []*Node {
+	var cells []*Node
+	for _, child := range row.Children {
+		if child.Is("td") || child.Is("th") {
+			cells = append(cells, child)
+		}
+	}
+	return cells
+}
+
+// 表格所有单元格的文本
+func (t Table) TextGrid() [][]string {
+	var grid [][]string
+	for _, row := range t.Rows() {
+		var texts []string
+		for _, cell := range Cells(row) {
+			texts = append(texts, cell.Text())
+		}
+		grid = append(grid, texts)
+	}
+	return grid
+}
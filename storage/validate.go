@@ -0,0 +1,366 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 诊断的严重程度
+type Severity int
+
+const (
+	SeverityError   Severity = iota //会导致发布失败或内容被破坏的错误
+	SeverityWarning                 //可能有问题的内容
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "警告"
+	}
+	return "错误"
+}
+
+// 校验的诊断信息
+type Diagnostic struct {
+	Line     int
+	Column   int
+	Severity Severity
+	Msg      string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Msg)
+}
+
+// 校验未通过的错误，包含所有错误级别的诊断信息
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		msgs = append(msgs, d.String())
+	}
+	return "Storage格式校验失败: " + strings.Join(msgs, "; ")
+}
+
+// 宏的参数和内容体规格
+type MacroSpec struct {
+	Params []string //已知的参数名，默认参数的名称为空字符串
+	Body   string   //内容体的类型：rich、plain，为空表示没有内容体
+}
+
+// ac:和ri:元素的规格
+type elementSpec struct {
+	attrs    []string //允许的属性
+	required []string //必需的属性
+	parents  []string //允许的父元素，为空时不限制
+}
+
+// Storage格式的校验器
+type Validator struct {
+	// 已知宏的规格，为空时使用DefaultMacros，未知的宏不检查参数
+	Macros map[string]MacroSpec
+
+	// 检查页面链接指向的页面是否存在，spaceKey为空表示当前空间，为空时不检查。
+	// 页面不存在时报告为警告，因为链接的页面可能稍后才会创建
+	PageExists func(spaceKey, title string) (bool, error)
+
+	// 是否为同一次发布中将要创建的页面，这些页面的链接不检查是否存在，为空时都检查
+	Planned func(spaceKey, title string) bool
+}
+
+// 常用宏的规格
+//
+// 提示面板允许provenance-id参数，用于标识页面末尾的溯源块
+var DefaultMacros = map[string]MacroSpec{
+	"info":            {Params: []string{"icon", "title", "provenance-id"}, Body: "rich"},
+	"note":            {Params: []string{"icon", "title", "provenance-id"}, Body: "rich"},
+	"warning":         {Params: []string{"icon", "title", "provenance-id"}, Body: "rich"},
+	"tip":             {Params: []string{"icon", "title", "provenance-id"}, Body: "rich"},
+	"code":            {Params: []string{"language", "title", "theme", "linenumbers", "firstline", "collapse"}, Body: "plain"},
+	"noformat":        {Params: []string{"title", "nopanel"}, Body: "plain"},
+	"expand":          {Params: []string{"title"}, Body: "rich"},
+	"toc":             {Params: []string{"minLevel", "maxLevel", "type", "style", "outline", "include", "exclude", "printable", "class", "absoluteUrl", "separator", "indent"}},
+	"children":        {Params: []string{"page", "all", "depth", "sort", "reverse", "style", "excerptType", "first"}},
+	"status":          {Params: []string{"colour", "title", "subtle"}},
+	"jira":            {Params: []string{"server", "serverId", "key", "jqlQuery", "columns", "columnIds", "maximumIssues", "count"}},
+	"panel":           {Params: []string{"title", "borderStyle", "borderColor", "borderWidth", "bgColor", "titleBGColor", "titleColor"}, Body: "rich"},
+	"anchor":          {Params: []string{""}},
+	"excerpt":         {Params: []string{"name", "hidden", "atlassian-macro-output-type"}, Body: "rich"},
+	"excerpt-inline":  {Params: []string{"name", "hidden"}, Body: "rich"},
+	"excerpt-include": {Params: []string{"", "name", "nopanel"}},
+	"include":         {Params: []string{""}},
+}
+
+// ac:和ri:元素的规格
+var storageElements = map[string]elementSpec{
+	"ac:structured-macro":     {attrs: []string{"ac:name", "ac:schema-version", "ac:macro-id", "ac:local-id", "data-layout"}, required: []string{"ac:name"}},
+	"ac:macro":                {attrs: []string{"ac:name"}, required: []string{"ac:name"}},
+	"ac:parameter":            {attrs: []string{"ac:name"}, parents: []string{"ac:structured-macro", "ac:macro"}},
+	"ac:default-parameter":    {parents: []string{"ac:macro"}},
+	"ac:rich-text-body":       {parents: []string{"ac:structured-macro", "ac:macro"}},
+	"ac:plain-text-body":      {parents: []string{"ac:structured-macro", "ac:macro"}},
+	"ac:link":                 {attrs: []string{"ac:anchor", "ac:tooltip", "ac:card-appearance"}},
+	"ac:link-body":            {parents: []string{"ac:link"}},
+	"ac:plain-text-link-body": {parents: []string{"ac:link"}},
+	"ac:image": {attrs: []string{"ac:align", "ac:border", "ac:class", "ac:title", "ac:style", "ac:thumbnail", "ac:alt",
+		"ac:height", "ac:width", "ac:vspace", "ac:hspace", "ac:queryparams", "ac:original-height", "ac:original-width",
+		"ac:custom-width", "ac:layout", "ac:src"}},
+	"ac:caption":               {parents: []string{"ac:image"}},
+	"ac:emoticon":              {attrs: []string{"ac:name", "ac:emoji-shortname", "ac:emoji-id", "ac:emoji-fallback"}, required: []string{"ac:name"}},
+	"ac:placeholder":           {attrs: []string{"ac:type"}},
+	"ac:task-list":             {},
+	"ac:task":                  {parents: []string{"ac:task-list"}},
+	"ac:task-id":               {parents: []string{"ac:task"}},
+	"ac:task-uuid":             {parents: []string{"ac:task"}},
+	"ac:task-status":           {parents: []string{"ac:task"}},
+	"ac:task-body":             {parents: []string{"ac:task"}},
+	"ac:layout":                {},
+	"ac:layout-section":        {attrs: []string{"ac:type", "ac:breakout-mode", "ac:breakout-width"}, parents: []string{"ac:layout"}},
+	"ac:layout-cell":           {parents: []string{"ac:layout-section"}},
+	"ac:inline-comment-marker": {attrs: []string{"ac:ref"}},
+	"ac:adf-extension":         {},
+	"ac:adf-node":              {attrs: []string{"type"}},
+	"ac:adf-attribute":         {attrs: []string{"key"}},
+	"ac:adf-content":           {},
+	"ac:adf-fallback":          {},
+	"ac:adf-mark":              {attrs: []string{"key"}},
+	"ri:page":                  {attrs: []string{"ri:content-title", "ri:space-key", "ri:version-at-save"}, required: []string{"ri:content-title"}},
+	"ri:blog-post":             {attrs: []string{"ri:content-title", "ri:space-key", "ri:posting-day", "ri:version-at-save"}, required: []string{"ri:content-title", "ri:posting-day"}},
+	"ri:attachment":            {attrs: []string{"ri:filename", "ri:version-at-save"}, required: []string{"ri:filename"}},
+	"ri:url":                   {attrs: []string{"ri:value"}, required: []string{"ri:value"}},
+	"ri:user":                  {attrs: []string{"ri:userkey", "ri:account-id", "ri:username", "ri:local-id"}},
+	"ri:space":                 {attrs: []string{"ri:space-key"}, required: []string{"ri:space-key"}},
+	"ri:shortcut":              {attrs: []string{"ri:key", "ri:parameter"}, required: []string{"ri:key"}},
+	"ri:content-entity":        {attrs: []string{"ri:content-id", "ri:version-at-save"}, required: []string{"ri:content-id"}},
+}
+
+// 资源元素（ri:）允许的父元素
+var resourceParents = []string{"ac:link", "ac:image", "ac:parameter", "ri:attachment", "ri:shortcut"}
+
+var entityRegexp = regexp.MustCompile(`^&(?:#[0-9]+|#[xX][0-9A-Fa-f]+|[A-Za-z][A-Za-z0-9]*);`)
+
+// 使用缺省规格校验，不检查页面链接
+func Validate(storage string) []Diagnostic {
+	diags, _ := (&Validator{}).Validate(storage)
+	return diags
+}
+
+// 校验Storage格式的内容，返回按位置排序的诊断信息
+//
+// 内容不是格式正确的XHTML时只返回解析错误。检查页面是否存在出错时返回错误
+func (v *Validator) Validate(storage string) ([]Diagnostic, error) {
+	doc, err := Parse(storage)
+	if err != nil {
+		if syntaxErr, ok := err.(*SyntaxError); ok {
+			return []Diagnostic{{Line: syntaxErr.Line, Column: syntaxErr.Column, Severity: SeverityError, Msg: syntaxErr.Msg}}, nil
+		}
+		return nil, err
+	}
+
+	c := &checker{v: v, pages: make(map[string]bool)}
+	doc.Walk(c.check)
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		a, b := c.diags[i], c.diags[j]
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	return c.diags, nil
+}
+
+// 诊断信息中是否包含错误
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// 只包含错误级别的诊断信息的ValidationError，没有错误时返回nil
+func ErrorOf(diags []Diagnostic) error {
+	var errs []Diagnostic
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Diagnostics: errs}
+}
+
+// 一次校验的状态
+type checker struct {
+	v     *Validator
+	diags []Diagnostic
+	pages map[string]bool //已检查的页面链接
+	err   error
+}
+
+// 添加节点处的诊断信息
+func (c *checker) report(node *Node, severity Severity, format string, args ...interface{}) {
+	c.diags = append(c.diags, Diagnostic{Line: node.Line, Column: node.Column, Severity: severity, Msg: fmt.Sprintf(format, args...)})
+}
+
+// 检查节点
+func (c *checker) check(node *Node) bool {
+	switch node.Type {
+	case TextNode:
+		c.checkEntities(node)
+	case ElementNode:
+		c.checkEntities(node)
+		c.checkElement(node)
+	}
+	return c.err == nil
+}
+
+// 检查原文中未转义的&
+func (c *checker) checkEntities(node *Node) {
+	raw := node.raw
+	for k := strings.IndexByte(raw, '&'); k >= 0; {
+		if !entityRegexp.MatchString(raw[k:]) {
+			line := node.Line + strings.Count(raw[:k], "\n")
+			col := node.Column + len([]rune(raw[:k]))
+			if nl := strings.LastIndexByte(raw[:k], '\n'); nl >= 0 {
+				col = len([]rune(raw[nl+1:k])) + 1
+			}
+			c.diags = append(c.diags, Diagnostic{Line: line, Column: col, Severity: SeverityError, Msg: "未转义的'&'，需要写为&amp;"})
+		}
+
+		next := strings.IndexByte(raw[k+1:], '&')
+		if next < 0 {
+			break
+		}
+		k += next + 1
+	}
+}
+
+// 检查元素
+func (c *checker) checkElement(node *Node) {
+	prefix := node.Prefix()
+	if prefix == "" {
+		return
+	}
+
+	if prefix != "ac" && prefix != "ri" {
+		c.report(node, SeverityWarning, "未知的命名空间前缀%s", prefix)
+		return
+	}
+
+	spec, found := storageElements[node.Name]
+	if !found {
+		c.report(node, SeverityError, "未知的元素<%s>", node.Name)
+		return
+	}
+
+	for _, attr := range node.Attrs {
+		if !contains(spec.attrs, attr.Name) {
+			c.report(node, SeverityWarning, "元素<%s>中未知的属性%s", node.Name, attr.Name)
+		}
+	}
+	for _, name := range spec.required {
+		if _, found := node.LookupAttr(name); !found {
+			c.report(node, SeverityError, "元素<%s>缺少属性%s", node.Name, name)
+		}
+	}
+
+	parents := spec.parents
+	if prefix == "ri" {
+		parents = resourceParents
+	}
+	if len(parents) > 0 && (node.Parent == nil || !contains(parents, node.Parent.Name)) {
+		c.report(node, SeverityError, "元素<%s>只能位于<%s>中", node.Name, strings.Join(parents, ">、<"))
+	}
+
+	switch node.Name {
+	case "ac:structured-macro":
+		c.checkMacro(Macro{node})
+	case "ri:page":
+		c.checkPageLink(node)
+	}
+}
+
+// 检查宏的参数和内容体
+func (c *checker) checkMacro(m Macro) {
+	macros := c.v.Macros
+	if macros == nil {
+		macros = DefaultMacros
+	}
+
+	spec, found := macros[m.MacroName()]
+	if !found {
+		return
+	}
+
+	for _, param := range m.ChildElements("ac:parameter") {
+		name := param.Attr("ac:name")
+		if !contains(spec.Params, name) {
+			c.report(param, SeverityWarning, "宏%s没有参数%q", m.MacroName(), name)
+		}
+	}
+
+	rich := m.Child("ac:rich-text-body") != nil
+	plain := m.Child("ac:plain-text-body") != nil
+	switch {
+	case spec.Body == "plain" && rich:
+		c.report(m.Node, SeverityError, "宏%s的内容需要使用ac:plain-text-body", m.MacroName())
+	case spec.Body == "rich" && plain:
+		c.report(m.Node, SeverityError, "宏%s的内容需要使用ac:rich-text-body", m.MacroName())
+	case spec.Body == "" && (rich || plain):
+		c.report(m.Node, SeverityWarning, "宏%s没有内容体", m.MacroName())
+	}
+}
+
+// 检查页面链接指向的页面是否存在
+func (c *checker) checkPageLink(node *Node) {
+	if c.v.PageExists == nil {
+		return
+	}
+
+	space := node.Attr("ri:space-key")
+	title := node.Attr("ri:content-title")
+	if title == "" {
+		return
+	}
+
+	if c.v.Planned != nil && c.v.Planned(space, title) {
+		return
+	}
+
+	key := space + "\x00" + title
+
+	exists, checked := c.pages[key]
+	if !checked {
+		var err error
+		exists, err = c.v.PageExists(space, title)
+		if err != nil {
+			c.err = fmt.Errorf("检查页面%s是否存在失败: %s", title, err)
+			return
+		}
+		c.pages[key] = exists
+	}
+
+	if !exists {
+		c.report(node, SeverityWarning, "链接的页面%q不存在", title)
+	}
+}
+
+// 列表中是否包含指定字符串
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
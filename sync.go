@@ -0,0 +1,335 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-http/confluence/storage"
+)
+
+// 页面同步的结果类型
+type SyncAction string
+
+const (
+	SyncCreated   SyncAction = "created"   //新建了页面
+	SyncUpdated   SyncAction = "updated"   //更新了页面内容
+	SyncUnchanged SyncAction = "unchanged" //内容和位置均无变化
+	SyncMoved     SyncAction = "moved"     //内容无变化，仅移动了位置
+)
+
+// 页面同步的结果
+type SyncResult struct {
+	Action  SyncAction
+	Content Content
+	Moved   bool //页面是否被移动到了新的父页面下
+
+	Diagnostics []storage.Diagnostic //发布前校验的诊断信息（包括警告）
+	Warnings    []string             //页面已保存，但保存内容哈希、页脚数据等附加信息失败
+}
+
+// 页脚渲染器，用于在页面末尾追加备注信息
+type FooterRenderer interface {
+	// 渲染页脚内容
+	Render(options *DrawModifyPageOption) (string, error)
+	// 从页面内容中去除页脚，用于对比内容变化
+	Strip(storage string) string
+}
+
+// 使用函数渲染的页脚，页脚以ConfluenceNoteSplite分隔
+type FooterFunc func(options *DrawModifyPageOption) (string, error)
+
+func (f FooterFunc) Render(options *DrawModifyPageOption) (string, error) {
+	return f(options)
+}
+
+func (f FooterFunc) Strip(storage string) string {
+	return stripNoteFooter(storage)
+}
+
+// 页面创建或更新后需要执行额外操作的页脚渲染器，如在内容属性中保存页脚数据
+type FooterSaver interface {
+	Saved(cli *Client, content Content, options *DrawModifyPageOption) error
+}
+
+// 内容变化检测器
+type ChangeDetector interface {
+	// 对比去除页脚后的原内容和新内容是否有变化
+	Changed(oldStorage, newStorage string) (bool, error)
+}
+
+// 通过服务端将内容转换为view格式后对比的变化检测器
+type RemoteChangeDetector struct {
+	Client *Client
+}
+
+func (d RemoteChangeDetector) Changed(oldStorage, newStorage string) (bool, error) {
+	newValue, err := d.Client.ContentBodyConvertTo(newStorage, "storage", "view")
+	if err != nil {
+		return false, fmt.Errorf("转换新内容失败: %s", err)
+	}
+
+	oldValue, err := d.Client.ContentBodyConvertTo(oldStorage, "storage", "view")
+	if err != nil {
+		return false, fmt.Errorf("转换旧内容失败: %s", err)
+	}
+
+	return newValue != oldValue, nil
+}
+
+// 页面位置策略，决定已存在的同名页面能否被同步以及是否需要移动
+type PlacementPolicy interface {
+	// 检查已存在的页面，返回是否需要移动到options.ParentId下
+	Place(existing Content, options *DrawModifyPageOption) (bool, error)
+}
+
+// 要求已存在的页面位于ConfluenceDirPrefix路径下，父页面不同时移动
+type PrefixPlacement struct{}
+
+func (PrefixPlacement) Place(existing Content, options *DrawModifyPageOption) (bool, error) {
+	if !strings.HasPrefix(pagePath(existing), options.ConfluenceDirPrefix) {
+		return false, fmt.Errorf("一个标题为 '%v' 的页面已经存在于该空间中。为您的页面输入一个不同的标题。", options.Title)
+	}
+
+	return needsMove(existing, options.ParentId), nil
+}
+
+// 不限制已存在页面的位置，父页面不同时移动
+type AnywherePlacement struct{}
+
+func (AnywherePlacement) Place(existing Content, options *DrawModifyPageOption) (bool, error) {
+	return needsMove(existing, options.ParentId), nil
+}
+
+// 不限制已存在页面的位置，也不移动页面
+type KeepPlacement struct{}
+
+func (KeepPlacement) Place(existing Content, options *DrawModifyPageOption) (bool, error) {
+	return false, nil
+}
+
+// 页面同步引擎，按指定的页脚、变化检测和位置策略同步页面
+type PageSync struct {
+	Client    *Client
+	Footer    FooterRenderer  //页脚渲染器，为空时不添加页脚
+	Detector  ChangeDetector  //变化检测器，缺省使用LocalChangeDetector
+	Placement PlacementPolicy //位置策略，缺省使用PrefixPlacement
+
+	//是否在页面属性中保存内容哈希，保存后页面未被他人修改时可直接通过哈希判断内容是否变化
+	StoreHash bool
+
+	//发布前的校验器，为空时不校验。校验出错误时不修改页面，返回storage.ValidationError
+	Validator *storage.Validator
+}
+
+// 创建使用缺省策略的页面同步引擎
+func NewPageSync(cli *Client) *PageSync {
+	return &PageSync{
+		Client:    cli,
+		Detector:  LocalChangeDetector{},
+		Placement: PrefixPlacement{},
+	}
+}
+
+// 从指定空间查找或创建指定标题的页面
+func (s *PageSync) Sync(options *DrawModifyPageOption) (SyncResult, error) {
+	//内容中的空行会被Confluence保存时自动去掉
+	//因此前先去掉，以避免对比内容变化时受到影响
+	data := strings.TrimSuffix(strings.TrimPrefix(options.Data, "\n"), "\n")
+
+	var diags []storage.Diagnostic
+	if s.Validator != nil {
+		var err error
+		diags, err = s.Validator.Validate(data)
+		if err != nil {
+			return SyncResult{}, fmt.Errorf("校验%s出错: %s", options.Title, err)
+		}
+
+		err = storage.ErrorOf(diags)
+		if err != nil {
+			return SyncResult{Diagnostics: diags}, err
+		}
+	}
+
+	//获取当前页面的内容
+	content, err := s.Client.ContentBySpaceAndTitle(options.Space, options.Title)
+	if err != nil {
+		return SyncResult{}, fmt.Errorf("查找%s出错: %s", options.Title, err)
+	}
+
+	// 不存在则创建
+	if content.Id == "" {
+		body, err := s.appendFooter(data, options)
+		if err != nil {
+			return SyncResult{}, err
+		}
+
+		content, err = s.Client.PageCreateInSpace(options.Space, options.ParentId, options.Title, body)
+		if err != nil {
+			return SyncResult{}, err
+		}
+
+		result := SyncResult{Action: SyncCreated, Content: content, Diagnostics: diags}
+		return s.afterSave(result, data, options), nil
+	}
+
+	placement := s.Placement
+	if placement == nil {
+		placement = PrefixPlacement{}
+	}
+
+	moved, err := placement.Place(content, options)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	//存在：去除原内容的页脚后对比内容是否有变化
+	oldValue := stripNoteFooter(content.Body.Storage.Value)
+	if s.Footer != nil {
+		oldValue = s.Footer.Strip(content.Body.Storage.Value)
+	}
+
+	changed, err := s.changed(content, oldValue, data)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	if !changed && !moved {
+		return SyncResult{Action: SyncUnchanged, Content: content, Diagnostics: diags}, nil
+	}
+
+	body, err := s.appendFooter(data, options)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	// 存在则否则更新
+	content.Space.Key = options.Space
+	content.Version.Number += 1
+	content.Version.Message = time.Now().Local().Format("机器人更新于2006-01-02 15:04:05")
+	content.SetStorageBody(body)
+
+	//设置父页面
+	if options.ParentId != "" {
+		content.Ancestors = []Content{
+			{
+				Id: options.ParentId,
+				Space: Space{
+					Key: content.Space.Key,
+				},
+			},
+		}
+	}
+
+	content, err = s.Client.ContentUpdate(content)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	action := SyncUpdated
+	if !changed {
+		action = SyncMoved
+	}
+
+	result := SyncResult{Action: action, Content: content, Moved: moved, Diagnostics: diags}
+	return s.afterSave(result, data, options), nil
+}
+
+// 判断内容是否有变化，开启StoreHash时优先使用页面上保存的哈希判断
+func (s *PageSync) changed(content Content, oldValue, data string) (bool, error) {
+	if s.StoreHash {
+		prop, err := s.Client.ContentPropertyByKey(content.Id, SyncHashPropertyKey)
+		if err != nil {
+			return false, fmt.Errorf("获取内容哈希失败: %s", err)
+		}
+
+		var stored SyncHash
+		if prop.Key != "" && json.Unmarshal(prop.Value, &stored) == nil &&
+			stored.Version == content.Version.Number && stored.Hash == StorageHash(data) {
+			return false, nil
+		}
+	}
+
+	detector := s.Detector
+	if detector == nil {
+		detector = LocalChangeDetector{}
+	}
+
+	return detector.Changed(oldValue, data)
+}
+
+// 页面保存后保存内容哈希和页脚数据
+//
+// 页面此时已经保存，失败时作为警告返回，不影响同步结果
+func (s *PageSync) afterSave(result SyncResult, data string, options *DrawModifyPageOption) SyncResult {
+	err := s.storeHash(result.Content, data)
+	if err != nil {
+		result.Warnings = append(result.Warnings, err.Error())
+	}
+
+	if saver, ok := s.Footer.(FooterSaver); ok {
+		err = saver.Saved(s.Client, result.Content, options)
+		if err != nil {
+			result.Warnings = append(result.Warnings, err.Error())
+		}
+	}
+	return result
+}
+
+// 开启StoreHash时在页面属性中保存内容哈希
+func (s *PageSync) storeHash(content Content, data string) error {
+	if !s.StoreHash {
+		return nil
+	}
+
+	hash := SyncHash{Hash: StorageHash(data), Version: content.Version.Number}
+	_, err := s.Client.ContentPropertySet(content.Id, SyncHashPropertyKey, hash)
+	if err != nil {
+		return fmt.Errorf("保存内容哈希失败: %s", err)
+	}
+
+	return nil
+}
+
+// 在内容末尾追加页脚
+func (s *PageSync) appendFooter(data string, options *DrawModifyPageOption) (string, error) {
+	if s.Footer == nil {
+		return data, nil
+	}
+
+	footer, err := s.Footer.Render(options)
+	if err != nil {
+		return "", fmt.Errorf("渲染页脚失败: %s", err)
+	}
+
+	return data + footer, nil
+}
+
+// 去除以ConfluenceNoteSplite分隔的备注宏
+func stripNoteFooter(storage string) string {
+	return strings.Split(storage, ConfluenceNoteSplite)[0]
+}
+
+// 页面在空间中的路径，由各级父页面的标题组成
+func pagePath(content Content) string {
+	path := ""
+	for _, ancestor := range content.Ancestors {
+		path += "/" + ancestor.Title
+	}
+	return path + "/" + content.Title
+}
+
+// 页面是否需要移动到指定的父页面下
+func needsMove(content Content, parentId string) bool {
+	if parentId == "" {
+		return false
+	}
+
+	lastAncestorId := ""
+	if n := len(content.Ancestors); n > 0 {
+		lastAncestorId = content.Ancestors[n-1].Id
+	}
+
+	return lastAncestorId != parentId
+}
@@ -0,0 +1,629 @@
+package confluence
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-http/confluence/storage"
+)
+
+// 单元格格式化函数，将单元格的值转换为Storage内容
+type CellFormat func(value string) string
+
+// 转义后的纯文本单元格，是缺省的格式
+func TextCell(value string) string {
+	return storage.EscapeText(value)
+}
+
+// 状态标签单元格，colours指定各个值的颜色，未指定的值为灰色，空值不显示标签
+func StatusCell(colours map[string]StatusColour) CellFormat {
+	return func(value string) string {
+		if value == "" {
+			return ""
+		}
+
+		colour, ok := colours[value]
+		if !ok {
+			colour = StatusGrey
+		}
+		return StatusMacro{Title: value, Colour: colour}.Storage()
+	}
+}
+
+// 外部链接单元格，href根据单元格的值生成链接地址
+func LinkCell(href func(value string) string) CellFormat {
+	return func(value string) string {
+		if value == "" {
+			return ""
+		}
+		return `<a href="` + storage.EscapeAttr(href(value)) + `">` + storage.EscapeText(value) + `</a>`
+	}
+}
+
+// 页面链接单元格，单元格的值为页面标题，spaceKey为空时为当前空间
+func PageLinkCell(spaceKey string) CellFormat {
+	return func(value string) string {
+		if value == "" {
+			return ""
+		}
+		return PageLink{Title: value, SpaceKey: spaceKey}.Storage()
+	}
+}
+
+// 表格的列配置
+type TableColumn struct {
+	Field  string     //字段名称：结构体的字段名或table标签、CSV的表头、JSON的键
+	Title  string     //表头文本，为空时使用Field
+	Group  string     //表头分组，相邻的同组列在上一行表头合并为一个单元格
+	Format CellFormat //单元格格式，为空时使用TextCell
+}
+
+func (c TableColumn) title() string {
+	if c.Title != "" {
+		return c.Title
+	}
+	return c.Field
+}
+
+func (c TableColumn) format(value string) string {
+	if c.Format != nil {
+		return c.Format(value)
+	}
+	return TextCell(value)
+}
+
+// 要发布为Confluence表格的数据
+type DataTable struct {
+	Columns []TableColumn
+	Rows    []map[string]string //每行各个字段的值
+
+	NoHeader bool   //不输出表头
+	SortBy   string //排序的字段，为空时保持原顺序。两个值都是数字时按数值比较
+	SortDesc bool   //是否降序排序
+	Key      string //更新已有表格时用于匹配行的字段，为空时使用第一列
+}
+
+// 从结构体切片创建表格
+//
+// columns为空时使用所有导出的字段，字段的table标签可以指定列名，标签为"-"时忽略该字段
+func TableFromStructs(rows interface{}, columns []TableColumn) (DataTable, error) {
+	v := reflect.ValueOf(rows)
+	if !v.IsValid() {
+		return DataTable{}, fmt.Errorf("数据为空，需要结构体切片")
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return DataTable{}, fmt.Errorf("不支持的数据类型%s，需要结构体切片", v.Type())
+	}
+
+	elem := v.Type().Elem()
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return DataTable{}, fmt.Errorf("不支持的数据类型%s，需要结构体切片", v.Type())
+	}
+
+	//字段名称到结构体字段下标的映射
+	fields := make(map[string]int)
+	var names []string
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("table"); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+
+		fields[name] = i
+		names = append(names, name)
+	}
+
+	if len(columns) == 0 {
+		for _, name := range names {
+			columns = append(columns, TableColumn{Field: name})
+		}
+	}
+
+	table := DataTable{Columns: columns}
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		for item.Kind() == reflect.Ptr && !item.IsNil() {
+			item = item.Elem()
+		}
+		if item.Kind() != reflect.Struct {
+			continue
+		}
+
+		row := make(map[string]string)
+		for _, column := range columns {
+			if j, ok := fields[column.Field]; ok {
+				row[column.Field] = cellValue(item.Field(j))
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
+
+// 从CSV创建表格，第一行为表头，columns为空时使用所有列
+func TableFromCSV(r io.Reader, columns []TableColumn) (DataTable, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return DataTable{}, fmt.Errorf("解析CSV失败: %s", err)
+	}
+	if len(records) == 0 {
+		return DataTable{Columns: columns}, nil
+	}
+
+	header := records[0]
+	if len(columns) == 0 {
+		for _, name := range header {
+			columns = append(columns, TableColumn{Field: name})
+		}
+	}
+
+	table := DataTable{Columns: columns}
+	for _, record := range records[1:] {
+		row := make(map[string]string)
+		for i, name := range header {
+			if i < len(record) {
+				row[name] = record[i]
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
+
+// 从JSON对象数组创建表格，columns为空时使用所有对象的键，按名称排序
+//
+// 字符串以外的值按JSON格式输出，null为空值
+func TableFromJSON(r io.Reader, columns []TableColumn) (DataTable, error) {
+	var items []map[string]json.RawMessage
+	err := json.NewDecoder(r).Decode(&items)
+	if err != nil {
+		return DataTable{}, fmt.Errorf("解析JSON失败: %s", err)
+	}
+
+	if len(columns) == 0 {
+		keys := make(map[string]bool)
+		var names []string
+		for _, item := range items {
+			for key := range item {
+				if !keys[key] {
+					keys[key] = true
+					names = append(names, key)
+				}
+			}
+		}
+
+		sort.Strings(names)
+		for _, name := range names {
+			columns = append(columns, TableColumn{Field: name})
+		}
+	}
+
+	table := DataTable{Columns: columns}
+	for _, item := range items {
+		row := make(map[string]string)
+		for key, raw := range item {
+			var s string
+			if json.Unmarshal(raw, &s) == nil {
+				row[key] = s
+			} else if string(raw) != "null" {
+				row[key] = string(raw)
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
+
+// 表格的Storage内容
+func (t DataTable) Storage() string {
+	var sb strings.Builder
+	sb.WriteString("<table>")
+
+	if !t.NoHeader {
+		sb.WriteString("<thead>")
+		sb.WriteString(t.headerRows())
+		sb.WriteString("</thead>")
+	}
+
+	sb.WriteString("<tbody>")
+	for _, row := range t.sortedRows() {
+		sb.WriteString("<tr>")
+		for _, column := range t.Columns {
+			sb.WriteString("<td>" + column.format(row[column.Field]) + "</td>")
+		}
+		sb.WriteString("</tr>")
+	}
+	sb.WriteString("</tbody></table>")
+
+	return sb.String()
+}
+
+// 表头行，有分组时输出两行，分组单元格横向合并，未分组的单元格纵向合并
+func (t DataTable) headerRows() string {
+	grouped := false
+	for _, column := range t.Columns {
+		if column.Group != "" {
+			grouped = true
+			break
+		}
+	}
+
+	if !grouped {
+		var sb strings.Builder
+		sb.WriteString("<tr>")
+		for _, column := range t.Columns {
+			sb.WriteString("<th>" + storage.EscapeText(column.title()) + "</th>")
+		}
+		sb.WriteString("</tr>")
+		return sb.String()
+	}
+
+	var top, bottom strings.Builder
+	for i := 0; i < len(t.Columns); {
+		column := t.Columns[i]
+		if column.Group == "" {
+			top.WriteString(`<th rowspan="2">` + storage.EscapeText(column.title()) + "</th>")
+			i++
+			continue
+		}
+
+		j := i
+		for ; j < len(t.Columns) && t.Columns[j].Group == column.Group; j++ {
+			bottom.WriteString("<th>" + storage.EscapeText(t.Columns[j].title()) + "</th>")
+		}
+
+		if j-i > 1 {
+			top.WriteString(`<th colspan="` + strconv.Itoa(j-i) + `">`)
+		} else {
+			top.WriteString("<th>")
+		}
+		top.WriteString(storage.EscapeText(column.Group) + "</th>")
+		i = j
+	}
+
+	return "<tr>" + top.String() + "</tr><tr>" + bottom.String() + "</tr>"
+}
+
+// 按SortBy排序后的行
+func (t DataTable) sortedRows() []map[string]string {
+	if t.SortBy == "" {
+		return t.Rows
+	}
+
+	rows := append([]map[string]string{}, t.Rows...)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return lessValue(rows[i][t.SortBy], rows[j][t.SortBy], t.SortDesc)
+	})
+	return rows
+}
+
+// 将表格的行更新到内容中的第index个表格（从0开始）
+//
+// 按Key字段匹配已有的行：匹配到时更新该行中配置了的列，其他列保持不变；未匹配到时添加新行。
+// 列通过表头文本与TableColumn.Title对应，表格没有表头或设置了NoHeader时按列的顺序对应。
+// 设置了SortBy时，更新后对表体的所有行重新排序
+func UpsertTableRows(body string, index int, table DataTable) (string, error) {
+	doc, err := storage.Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("解析页面内容失败: %s", err)
+	}
+
+	tables := doc.Tables()
+	if index < 0 || index >= len(tables) {
+		return "", fmt.Errorf("未找到第%d个表格", index+1)
+	}
+	target := tables[index]
+
+	//区分表头行和表体行
+	var headers, rows []*storage.Node
+	for _, row := range target.Rows() {
+		if len(rows) == 0 && !table.NoHeader && isHeaderRow(row) {
+			headers = append(headers, row)
+		} else {
+			rows = append(rows, row)
+		}
+	}
+
+	//表格的列位置到列配置的映射
+	width := 0
+	positions := make(map[string]int)
+	if len(headers) > 0 {
+		titles := headerTitles(headers)
+		width = len(titles)
+		for i, title := range titles {
+			positions[title] = i
+		}
+	} else {
+		width = len(table.Columns)
+		for i, column := range table.Columns {
+			positions[column.title()] = i
+		}
+		for _, row := range rows {
+			if n := len(storage.Cells(row)); n > width {
+				width = n
+			}
+		}
+	}
+
+	columns := make(map[int]TableColumn)
+	for _, column := range table.Columns {
+		if i, ok := positions[column.title()]; ok {
+			columns[i] = column
+		}
+	}
+
+	key := table.Key
+	if key == "" && len(table.Columns) > 0 {
+		key = table.Columns[0].Field
+	}
+
+	keyPos := -1
+	for i, column := range columns {
+		if column.Field == key {
+			keyPos = i
+		}
+	}
+	if keyPos < 0 {
+		return "", fmt.Errorf("表格中没有%s列", key)
+	}
+
+	//按关键列单元格的关键值索引已有的行
+	existing := make(map[string]*storage.Node)
+	for _, row := range rows {
+		cells := storage.Cells(row)
+		if keyPos < len(cells) {
+			existing[cellKey(cells[keyPos])] = row
+		}
+	}
+
+	container := target.Node
+	if len(rows) > 0 {
+		container = rows[len(rows)-1].Parent
+	} else if tbody := target.Child("tbody"); tbody != nil {
+		container = tbody
+	} else if target.Child("thead") != nil {
+		container = storage.NewElement("tbody")
+		target.AppendChild(container)
+	}
+
+	for _, data := range table.Rows {
+		//数据的关键值按关键列的格式生成后再比较，与已有单元格的关键值一致
+		keyCell, err := formatCell(columns[keyPos], data[key])
+		if err != nil {
+			return "", err
+		}
+
+		rowKey := cellKey(keyCell)
+		row := existing[rowKey]
+		if row == nil {
+			row = storage.NewElement("tr")
+			for i := 0; i < width; i++ {
+				row.AppendChild(storage.NewElement("td"))
+			}
+			container.AppendChild(row)
+			rows = append(rows, row)
+			existing[rowKey] = row
+		}
+
+		cells := storage.Cells(row)
+		for i, column := range columns {
+			//行的单元格数量不足时补齐
+			for len(cells) <= i {
+				cell := storage.NewElement("td")
+				row.AppendChild(cell)
+				cells = append(cells, cell)
+			}
+
+			cell, err := formatCell(column, data[column.Field])
+			if err != nil {
+				return "", err
+			}
+			cells[i].SetChildren(append([]*storage.Node{}, cell.Children...)...)
+		}
+	}
+
+	if table.SortBy != "" {
+		sortTableRows(rows, columns, table)
+	}
+
+	return doc.String(), nil
+}
+
+// 按列的格式生成单元格
+func formatCell(column TableColumn, value string) (*storage.Node, error) {
+	nodes, err := storage.ParseFragment(column.format(value))
+	if err != nil {
+		return nil, fmt.Errorf("解析%s列的单元格内容失败: %s", column.title(), err)
+	}
+
+	cell := storage.NewElement("td")
+	cell.SetChildren(nodes...)
+	return cell, nil
+}
+
+// 单元格的关键值：包含页面链接时为链接的页面标题，否则为去除首尾空白的文本
+func cellKey(cell *storage.Node) string {
+	for _, link := range cell.Links() {
+		if title := link.PageTitle(); title != "" {
+			return title
+		}
+	}
+	return strings.TrimSpace(cell.Text())
+}
+
+// 只更新页面中第index个表格的行，其他内容保持不变，版本冲突时重试
+func (cli *Client) ContentTableUpsert(id string, index int, table DataTable) (Content, error) {
+	return cli.ContentUpdateFunc(id, func(content *Content) error {
+		body, err := UpsertTableRows(content.Body.Storage.Value, index, table)
+		if err != nil {
+			return err
+		}
+
+		content.Body.Storage.Value = body
+		content.Version.Message = "更新表格数据"
+		return nil
+	})
+}
+
+// 按排序字段对同一父节点下的表体行重新排序
+func sortTableRows(rows []*storage.Node, columns map[int]TableColumn, table DataTable) {
+	pos := -1
+	for i, column := range columns {
+		if column.Field == table.SortBy {
+			pos = i
+		}
+	}
+	if pos < 0 {
+		return
+	}
+
+	text := func(row *storage.Node) string {
+		cells := storage.Cells(row)
+		if pos < len(cells) {
+			return strings.TrimSpace(cells[pos].Text())
+		}
+		return ""
+	}
+
+	parents := make(map[*storage.Node][]*storage.Node)
+	var order []*storage.Node
+	for _, row := range rows {
+		if _, ok := parents[row.Parent]; !ok {
+			order = append(order, row.Parent)
+		}
+		parents[row.Parent] = append(parents[row.Parent], row)
+	}
+
+	for _, parent := range order {
+		group := parents[parent]
+		sorted := append([]*storage.Node{}, group...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return lessValue(text(sorted[i]), text(sorted[j]), table.SortDesc)
+		})
+
+		inGroup := make(map[*storage.Node]bool, len(group))
+		for _, row := range group {
+			inGroup[row] = true
+		}
+
+		//表体行的位置不变，依次放入排序后的行，同一父节点下的表头行保持原样
+		var children []*storage.Node
+		k := 0
+		for _, child := range parent.Children {
+			if inGroup[child] {
+				children = append(children, sorted[k])
+				k++
+			} else {
+				children = append(children, child)
+			}
+		}
+		parent.SetChildren(children...)
+	}
+}
+
+// 是否为全部由th组成的表头行
+func isHeaderRow(row *storage.Node) bool {
+	cells := storage.Cells(row)
+	if len(cells) == 0 {
+		return false
+	}
+	for _, cell := range cells {
+		if !cell.Is("th") {
+			return false
+		}
+	}
+	return true
+}
+
+// 根据表头行计算每一列的标题，合并的单元格使用最下层的表头
+func headerTitles(headers []*storage.Node) []string {
+	var titles []string
+	spans := make(map[int]int) //被上方单元格纵向合并的列还需跨越的行数
+
+	for _, row := range headers {
+		col := 0
+		next := func() {
+			for spans[col] > 0 {
+				spans[col]--
+				col++
+			}
+		}
+
+		for _, cell := range storage.Cells(row) {
+			next()
+			colspan, _ := strconv.Atoi(cell.Attr("colspan"))
+			rowspan, _ := strconv.Atoi(cell.Attr("rowspan"))
+			if colspan < 1 {
+				colspan = 1
+			}
+
+			text := strings.TrimSpace(cell.Text())
+			for i := 0; i < colspan; i++ {
+				for len(titles) <= col {
+					titles = append(titles, "")
+				}
+				titles[col] = text
+				if rowspan > 1 {
+					spans[col] = rowspan - 1
+				}
+				col++
+			}
+		}
+		next()
+	}
+
+	return titles
+}
+
+// 比较两个单元格的值，都是数字时按数值比较
+func lessValue(a, b string, desc bool) bool {
+	if desc {
+		a, b = b, a
+	}
+
+	x, err1 := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	y, err2 := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if err1 == nil && err2 == nil {
+		return x < y
+	}
+	return a < b
+}
+
+// 结构体字段的文本值
+func cellValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04:05")
+	}
+
+	return fmt.Sprint(v.Interface())
+}
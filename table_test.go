@@ -0,0 +1,95 @@
+package confluence
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-http/confluence/storage"
+)
+
+// 表头行位于tbody中时，排序只移动表体行
+func TestUpsertTableRowsSortWithHeaderInBody(t *testing.T) {
+	body := `<table><tbody><tr><th>Name</th><th>Count</th></tr>` +
+		`<tr><td>b</td><td>2</td></tr>` +
+		`<tr><td>c</td><td>3</td></tr></tbody></table>`
+
+	table := DataTable{
+		Columns: []TableColumn{{Field: "Name"}, {Field: "Count"}},
+		Rows:    []map[string]string{{"Name": "a", "Count": "1"}, {"Name": "c", "Count": "30"}},
+		SortBy:  "Name",
+	}
+
+	got, err := UpsertTableRows(body, 0, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<table><tbody><tr><th>Name</th><th>Count</th></tr>` +
+		`<tr><td>a</td><td>1</td></tr>` +
+		`<tr><td>b</td><td>2</td></tr>` +
+		`<tr><td>c</td><td>30</td></tr></tbody></table>`
+	if got != want {
+		t.Errorf("更新结果为\n%s\n期望\n%s", got, want)
+	}
+
+	table.SortDesc = true
+	got, err = UpsertTableRows(body, 0, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = `<table><tbody><tr><th>Name</th><th>Count</th></tr>` +
+		`<tr><td>c</td><td>30</td></tr>` +
+		`<tr><td>b</td><td>2</td></tr>` +
+		`<tr><td>a</td><td>1</td></tr></tbody></table>`
+	if got != want {
+		t.Errorf("倒序更新结果为\n%s\n期望\n%s", got, want)
+	}
+}
+
+func TestTableFromStructsNil(t *testing.T) {
+	_, err := TableFromStructs(nil, nil)
+	if err == nil {
+		t.Error("nil数据应返回错误")
+	}
+}
+
+// 关键列为页面链接时，按链接的页面标题匹配已有的行
+func TestUpsertTableRowsPageLinkKey(t *testing.T) {
+	table := DataTable{
+		Columns: []TableColumn{{Field: "Page", Format: PageLinkCell("")}, {Field: "Owner"}},
+		Rows:    []map[string]string{{"Page": "A & B", "Owner": "x"}, {"Page": "C", "Owner": "y"}},
+	}
+
+	body := table.Storage()
+
+	table.Rows = []map[string]string{{"Page": "A & B", "Owner": "z"}, {"Page": "D", "Owner": "w"}}
+	got, err := UpsertTableRows(body, 0, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//再次更新相同的数据时内容不变
+	again, err := UpsertTableRows(got, 0, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != got {
+		t.Errorf("重复更新后的内容为\n%s\n期望\n%s", again, got)
+	}
+
+	doc, err := storage.Parse(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var owners []string
+	for _, row := range doc.Tables()[0].Rows()[1:] {
+		cells := storage.Cells(row)
+		owners = append(owners, cellKey(cells[0])+"="+cells[1].Text())
+	}
+	want := []string{"A & B=z", "C=y", "D=w"}
+	if !reflect.DeepEqual(owners, want) {
+		t.Errorf("表格的行为%v，期望%v", owners, want)
+	}
+}
@@ -0,0 +1,71 @@
+package confluence
+
+import (
+	"html"
+	"regexp"
+)
+
+// Confluence的内容模板（包括空间模板和蓝图模板）
+type ContentTemplate struct {
+	TemplateId           string              `json:"templateId,omitempty"`
+	Name                 string              `json:"name,omitempty"`
+	Description          string              `json:"description,omitempty"`
+	TemplateType         string              `json:"templateType,omitempty"`
+	EditorVersion        string              `json:"editorVersion,omitempty"`
+	ReferencingBlueprint string              `json:"referencingBlueprint,omitempty"`
+	OriginalTemplate     *TemplateModule     `json:"originalTemplate,omitempty"`
+	Space                *Space              `json:"space,omitempty"`
+	Labels               []Label             `json:"labels,omitempty"`
+	Body                 *ContentBody        `json:"body,omitempty"`
+	Links                *LinkResp           `json:"_links,omitempty"`
+	Expandable           *ExpandableResponse `json:"_expandable,omitempty"`
+}
+
+// 蓝图模板所属的插件模块
+type TemplateModule struct {
+	PluginKey string `json:"pluginKey,omitempty"`
+	ModuleKey string `json:"moduleKey,omitempty"`
+}
+
+// Confluence的标签
+type Label struct {
+	Prefix string `json:"prefix,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Id     string `json:"id,omitempty"`
+	Label  string `json:"label,omitempty"`
+}
+
+const (
+	TemplateTypePage = "page" //页面模板
+)
+
+var (
+	templateDeclarationsRegexp = regexp.MustCompile(`(?s)<at:declarations>.*?</at:declarations>`)
+	templateVarRegexp          = regexp.MustCompile(`(?s)<at:var\s+[^>]*?at:name="([^"]*)"[^>]*?(?:/>|>.*?</at:var>)`)
+)
+
+// 设置Storage类型的模板内容
+func (tpl *ContentTemplate) SetStorageBody(value string) {
+	if tpl.Body == nil {
+		tpl.Body = &ContentBody{}
+	}
+	tpl.Body.Storage.Representation = "storage"
+	tpl.Body.Storage.Value = value
+}
+
+// 使用指定的变量渲染模板，返回可直接用于创建页面的Storage内容
+//
+// 模板中的变量声明(at:declarations)会被移除，变量引用(at:var)会被替换为转义后的变量值，
+// 未提供值的变量替换为空字符串
+func (tpl *ContentTemplate) Render(vars map[string]string) string {
+	if tpl.Body == nil {
+		return ""
+	}
+
+	data := templateDeclarationsRegexp.ReplaceAllString(tpl.Body.Storage.Value, "")
+
+	return templateVarRegexp.ReplaceAllStringFunc(data, func(s string) string {
+		name := templateVarRegexp.FindStringSubmatch(s)[1]
+		return html.EscapeString(vars[html.UnescapeString(name)])
+	})
+}
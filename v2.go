@@ -0,0 +1,260 @@
+package confluence
+
+import (
+	"strconv"
+	"strings"
+)
+
+// v2接口中的版本信息
+type v2Version struct {
+	Number    int    `json:"number,omitempty"`
+	Message   string `json:"message,omitempty"`
+	MinorEdit bool   `json:"minorEdit,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+	AuthorId  string `json:"authorId,omitempty"`
+}
+
+// v2接口中的内容体
+type v2Body struct {
+	Storage    *ContentBodyStorage `json:"storage,omitempty"`
+	View       *ContentBodyStorage `json:"view,omitempty"`
+	ExportView *ContentBodyStorage `json:"export_view,omitempty"`
+}
+
+// v2接口写入时使用的内容体
+type v2BodyWrite struct {
+	Representation string `json:"representation"`
+	Value          string `json:"value"`
+}
+
+// v2接口中的链接信息
+type v2Links struct {
+	WebUI    string `json:"webui,omitempty"`
+	Download string `json:"download,omitempty"`
+	Base     string `json:"base,omitempty"`
+}
+
+// v2接口中的页面和博客
+type v2Page struct {
+	Id         string     `json:"id,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	Title      string     `json:"title,omitempty"`
+	SpaceId    string     `json:"spaceId,omitempty"`
+	ParentId   string     `json:"parentId,omitempty"`
+	ParentType string     `json:"parentType,omitempty"`
+	Position   *int       `json:"position,omitempty"`
+	Version    *v2Version `json:"version,omitempty"`
+	Body       *v2Body    `json:"body,omitempty"`
+	Links      v2Links    `json:"_links,omitempty"`
+}
+
+// v2接口写入页面时的请求数据
+type v2PageWrite struct {
+	Id       string      `json:"id,omitempty"`
+	Status   string      `json:"status"`
+	Title    string      `json:"title"`
+	SpaceId  string      `json:"spaceId,omitempty"`
+	ParentId string      `json:"parentId,omitempty"`
+	Body     v2BodyWrite `json:"body"`
+	Version  *v2Version  `json:"version,omitempty"`
+}
+
+// v2接口中的空间
+type v2Space struct {
+	Id          string `json:"id,omitempty"`
+	Key         string `json:"key,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Status      string `json:"status,omitempty"`
+	HomepageId  string `json:"homepageId,omitempty"`
+	Description *struct {
+		Plain *RepresentationValue `json:"plain,omitempty"`
+		View  *RepresentationValue `json:"view,omitempty"`
+	} `json:"description,omitempty"`
+	Links v2Links `json:"_links,omitempty"`
+}
+
+// v2接口中的附件
+type v2Attachment struct {
+	Id           string     `json:"id,omitempty"`
+	Status       string     `json:"status,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	MediaType    string     `json:"mediaType,omitempty"`
+	FileSize     int64      `json:"fileSize,omitempty"`
+	Comment      string     `json:"comment,omitempty"`
+	PageId       string     `json:"pageId,omitempty"`
+	BlogPostId   string     `json:"blogPostId,omitempty"`
+	DownloadLink string     `json:"downloadLink,omitempty"`
+	Version      *v2Version `json:"version,omitempty"`
+	Links        v2Links    `json:"_links,omitempty"`
+}
+
+// v2接口中的评论
+type v2Comment struct {
+	Id      string     `json:"id,omitempty"`
+	Status  string     `json:"status,omitempty"`
+	Title   string     `json:"title,omitempty"`
+	PageId  string     `json:"pageId,omitempty"`
+	Version *v2Version `json:"version,omitempty"`
+	Body    *v2Body    `json:"body,omitempty"`
+	Links   v2Links    `json:"_links,omitempty"`
+}
+
+// v2版本信息转换为通用的版本信息
+func (v *v2Version) toVersion() Version {
+	if v == nil {
+		return Version{}
+	}
+
+	return Version{
+		Number:    v.Number,
+		Message:   v.Message,
+		MinorEdit: v.MinorEdit,
+		When:      v.CreatedAt,
+	}
+}
+
+// v2内容体转换为通用的内容体
+func (b *v2Body) toContentBody() ContentBody {
+	var body ContentBody
+	if b == nil {
+		return body
+	}
+
+	if b.Storage != nil {
+		body.Storage = *b.Storage
+	}
+	if b.View != nil {
+		body.View = b.View
+	}
+	if b.ExportView != nil {
+		body.ExportView = b.ExportView
+	}
+
+	return body
+}
+
+// v2页面转换为通用的内容
+func (p *v2Page) toContent(contentType string) Content {
+	content := Content{
+		Id:      p.Id,
+		Type:    contentType,
+		Status:  p.Status,
+		Title:   p.Title,
+		Body:    p.Body.toContentBody(),
+		Version: p.Version.toVersion(),
+		Link: LinkResp{
+			WebUI: p.Links.WebUI,
+			Base:  p.Links.Base,
+		},
+	}
+	content.Space.Id, _ = strconv.Atoi(p.SpaceId)
+
+	if p.Position != nil {
+		content.Extensions = &ContentExtensions{Position: float64(*p.Position)}
+	}
+
+	return content
+}
+
+// v2空间转换为通用的空间
+func (s *v2Space) toSpace() Space {
+	space := Space{
+		Key:   s.Key,
+		Name:  s.Name,
+		Type:  s.Type,
+		Links: &LinkResp{WebUI: s.Links.WebUI},
+	}
+	space.Id, _ = strconv.Atoi(s.Id)
+
+	if s.HomepageId != "" {
+		space.HomePage = &Content{Id: s.HomepageId, Type: ContentTypePage}
+	}
+
+	if s.Description != nil {
+		space.Description = &SpaceDescription{}
+		if s.Description.Plain != nil {
+			space.Description.Plain = *s.Description.Plain
+		}
+		if s.Description.View != nil {
+			space.Description.View = *s.Description.View
+		}
+	}
+
+	return space
+}
+
+// v2附件转换为通用的附件
+func (a *v2Attachment) toAttachment() Attachment {
+	att := Attachment{
+		Id:      a.Id,
+		Type:    "attachment",
+		Status:  a.Status,
+		Title:   a.Title,
+		Version: a.Version.toVersion(),
+		Extensions: AttachmentExtensions{
+			MediaType: a.MediaType,
+			FileSize:  a.FileSize,
+			Comment:   a.Comment,
+		},
+		Link: LinkResp{
+			WebUI:    a.Links.WebUI,
+			Download: a.DownloadLink,
+		},
+	}
+
+	if a.PageId != "" {
+		att.Container = &Content{Id: a.PageId, Type: ContentTypePage}
+	}
+
+	return att
+}
+
+// v2评论转换为通用的内容
+func (c *v2Comment) toContent() Content {
+	comment := Content{
+		Id:      c.Id,
+		Type:    "comment",
+		Status:  c.Status,
+		Title:   c.Title,
+		Body:    c.Body.toContentBody(),
+		Version: c.Version.toVersion(),
+		Link:    LinkResp{WebUI: c.Links.WebUI},
+	}
+
+	if c.PageId != "" {
+		comment.Container = &Content{Id: c.PageId, Type: ContentTypePage}
+	}
+
+	return comment
+}
+
+// 内容类型对应的v2接口路径
+func v2ContentPath(contentType string) string {
+	switch contentType {
+	case ContentTypeBlog, "blogpost":
+		return "/blogposts"
+	default:
+		return "/pages"
+	}
+}
+
+// 从expand参数中获取v2接口需要的body-format
+func v2BodyFormat(expand string) string {
+	for _, field := range strings.Split(expand, ",") {
+		if strings.HasPrefix(field, "body.") {
+			return strings.TrimPrefix(field, "body.")
+		}
+	}
+	return ""
+}
+
+// expand参数中是否包含指定的字段
+func expandHas(expand, name string) bool {
+	for _, field := range strings.Split(expand, ",") {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
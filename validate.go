@@ -0,0 +1,22 @@
+package confluence
+
+import (
+	"github.com/go-http/confluence/storage"
+)
+
+// 创建检查页面链接的Storage格式校验器，未指定空间的页面链接在space空间中查找
+func (cli *Client) StorageValidator(space string) *storage.Validator {
+	return &storage.Validator{
+		PageExists: func(spaceKey, title string) (bool, error) {
+			if spaceKey == "" {
+				spaceKey = space
+			}
+
+			content, err := cli.ContentBySpaceAndTitle(spaceKey, title)
+			if err != nil {
+				return false, err
+			}
+			return content.Id != "", nil
+		},
+	}
+}